@@ -0,0 +1,88 @@
+package netconf
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// PasswordChangeRequiredError is returned when connecting with a
+// KeyboardInteractiveConfig, if the device asks for a new password,
+// usually because the current one expired, and the answer function
+// declined to give one. It is distinct from the error returned when
+// the credentials are rejected.
+//
+// Depending on the version of golang.org/x/crypto/ssh, the error may
+// be wrapped by the SSH handshake error, so it should be matched with
+// errors.As.
+type PasswordChangeRequiredError struct {
+	Instruction string   // Instruction is the instruction sent by the device.
+	Prompts     []string // Prompts are the prompts the device asked to answer.
+}
+
+// Error is PasswordChangeRequiredError's implementation of the error interface.
+func (e *PasswordChangeRequiredError) Error() string {
+	return fmt.Sprintf("netconf: device requires a password change: %q", e.Prompts)
+}
+
+// KeyboardInteractiveConfig returns an ssh.ClientConfig that authenticates
+// the user with keyboard-interactive authentication, for devices that force
+// a password change on login, or that don't accept password authentication.
+//
+// The answer function is called with the prompts of every challenge sent by
+// the device, and returns one answer for each. To complete a forced password
+// change, it answers the prompts for the new password. If it returns nil for
+// a challenge asking for a new password, authentication is aborted with a
+// PasswordChangeRequiredError.
+//
+// The returned config has no HostKeyCallback, which must be set before use.
+func KeyboardInteractiveConfig(user string, answer func(prompts []string) []string) *ssh.ClientConfig {
+	return &ssh.ClientConfig{
+		User: user,
+		Auth: []ssh.AuthMethod{
+			ssh.KeyboardInteractive(keyboardInteractiveChallenge(answer)),
+		},
+	}
+}
+
+// keyboardInteractiveChallenge answers challenges with the answer function.
+func keyboardInteractiveChallenge(answer func(prompts []string) []string) ssh.KeyboardInteractiveChallenge {
+	return func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+
+		// some servers send empty challenges, only to show an instruction
+		if len(questions) == 0 {
+			return nil, nil
+		}
+
+		answers := answer(questions)
+		if answers == nil && isPasswordChange(instruction, questions) {
+			return nil, &PasswordChangeRequiredError{
+				Instruction: instruction,
+				Prompts:     questions,
+			}
+		}
+
+		if len(answers) != len(questions) {
+			return nil, fmt.Errorf("netconf: %d answers given to %d keyboard-interactive prompts",
+				len(answers), len(questions))
+		}
+
+		return answers, nil
+	}
+}
+
+// isPasswordChange reports whether a keyboard-interactive challenge asks
+// for a new password, which devices phrase in many ways, like "New
+// password:", or "Your password has expired".
+func isPasswordChange(instruction string, questions []string) bool {
+	for _, text := range append([]string{instruction}, questions...) {
+		text = strings.ToLower(text)
+		if strings.Contains(text, "new password") ||
+			strings.Contains(text, "expired") ||
+			strings.Contains(text, "change your password") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package netconf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeyboardInteractiveConfig(t *testing.T) {
+
+	config := KeyboardInteractiveConfig("admin", func(prompts []string) []string { return nil })
+	if config.User != "admin" || len(config.Auth) != 1 {
+		t.Errorf("unexpected config: %+v", config)
+	}
+}
+
+func TestKeyboardInteractiveChallenge(t *testing.T) {
+
+	challenge := keyboardInteractiveChallenge(func(prompts []string) []string {
+		if prompts[0] == "Password:" {
+			return []string{"secret"}
+		}
+		return nil
+	})
+
+	if answers, err := challenge("", "", []string{"Password:"}, []bool{false}); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual([]string{"secret"}, answers) {
+		t.Errorf("unexpected answers\nwant:\t%q\ngot:\t%q", []string{"secret"}, answers)
+	}
+
+	prompts := []string{"New password:", "Retype new password:"}
+	_, err := challenge("", "Your password has expired", prompts, []bool{false, false})
+	if changeErr, ok := err.(*PasswordChangeRequiredError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", changeErr, err)
+	} else if !reflect.DeepEqual(prompts, changeErr.Prompts) {
+		t.Errorf("unexpected prompts\nwant:\t%q\ngot:\t%q", prompts, changeErr.Prompts)
+	}
+
+	// declining an unrelated prompt is not a password change
+	_, err = challenge("", "", []string{"Token:"}, []bool{true})
+	if _, ok := err.(*PasswordChangeRequiredError); ok || err == nil {
+		t.Errorf("unexpected error for a declined prompt: %v", err)
+	}
+}
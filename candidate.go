@@ -0,0 +1,201 @@
+package netconf
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// CapabilityConfirmedCommit is the capability a server advertises when
+// it supports confirmed commits.
+const CapabilityConfirmedCommit = "urn:ietf:params:netconf:capability:confirmed-commit:1.1"
+
+// commitOperation models the commit operation.
+type commitOperation struct {
+	XMLName        xml.Name  `xml:"commit"`
+	Confirmed      *struct{} `xml:"confirmed,omitempty"`
+	ConfirmTimeout uint      `xml:"confirm-timeout,omitempty"`
+}
+
+// discardChangesOperation models the discard-changes operation.
+type discardChangesOperation struct {
+	XMLName xml.Name `xml:"discard-changes"`
+}
+
+// Commit returns a Method that commits the candidate datastore
+// into the running datastore.
+func Commit() *Method {
+	return WrapMethod(&commitOperation{})
+}
+
+// ConfirmedCommit returns a Method that commits the candidate datastore,
+// but the server reverts the commit unless another commit confirms it
+// within the given timeout. The timeout is rounded down to seconds, and
+// the server's default of 600 seconds is used when it is less than a
+// second. The server must advertise CapabilityConfirmedCommit.
+func ConfirmedCommit(timeout time.Duration) *Method {
+	return WrapMethod(&commitOperation{
+		Confirmed:      &struct{}{},
+		ConfirmTimeout: uint(timeout / time.Second),
+	})
+}
+
+// DiscardChanges returns a Method that reverts the candidate
+// datastore to the current running configuration.
+func DiscardChanges() *Method {
+	return WrapMethod(&discardChangesOperation{})
+}
+
+// cancelCommitOperation models the cancel-commit operation.
+type cancelCommitOperation struct {
+	XMLName   xml.Name `xml:"cancel-commit"`
+	PersistID string   `xml:"persist-id,omitempty"`
+}
+
+// CancelCommit returns a Method that cancels an ongoing confirmed commit,
+// reverting the running datastore to its state before the commit, without
+// waiting for the confirm timeout. The persistID must be given if the
+// commit was made persistent, and empty otherwise, in which case the
+// commit must have been issued by the same session. The server must
+// advertise CapabilityConfirmedCommit.
+func CancelCommit(persistID string) *Method {
+	return WrapMethod(&cancelCommitOperation{
+		PersistID: persistID,
+	})
+}
+
+// ApplyOption configures ApplyCandidate.
+type ApplyOption func(*applyOptions)
+
+// applyOptions holds the options of ApplyCandidate.
+type applyOptions struct {
+	confirmed      bool
+	confirmTimeout time.Duration
+	skipValidate   bool
+}
+
+// WithConfirmedCommit makes ApplyCandidate commit with ConfirmedCommit,
+// so the server reverts the change unless it is confirmed within the
+// given timeout.
+func WithConfirmedCommit(timeout time.Duration) ApplyOption {
+	return func(o *applyOptions) {
+		o.confirmed = true
+		o.confirmTimeout = timeout
+	}
+}
+
+// WithoutValidate makes ApplyCandidate skip the validate operation, for
+// servers that don't advertise CapabilityValidate.
+func WithoutValidate() ApplyOption {
+	return func(o *applyOptions) {
+		o.skipValidate = true
+	}
+}
+
+// CandidateError is returned by ApplyCandidate when one of its
+// operations fails.
+type CandidateError struct {
+	Phase string // Phase is the operation that failed, e.g. lock or commit.
+	Err   error  // Err is the error returned by the operation, usually a ReplyError.
+
+	// CleanupErr is the first error returned while discarding the changes
+	// and unlocking the candidate after the failure, if any, in which case
+	// the candidate may still hold the changes, or still be locked. It is
+	// a CandidateError naming the cleanup operation.
+	CleanupErr error
+}
+
+// Error is CandidateError's implementation of the error interface.
+func (e *CandidateError) Error() string {
+	if e.CleanupErr != nil {
+		return fmt.Sprintf("netconf: candidate %s failed: %v (cleanup failed: %v)", e.Phase, e.Err, e.CleanupErr)
+	}
+	return fmt.Sprintf("netconf: candidate %s failed: %v", e.Phase, e.Err)
+}
+
+// Unwrap returns the error returned by the failed operation.
+func (e *CandidateError) Unwrap() error {
+	return e.Err
+}
+
+// cleanupTimeout bounds the operations undoing a failed change, which
+// don't use the caller's context, since it may be the cause of the failure.
+const cleanupTimeout = 10 * time.Second
+
+// cleanup discards the changes to the datastore, if discard is set, and
+// unlocks it, on a best effort basis, under a context of its own, bound by
+// cleanupTimeout. It returns the first error, as a CandidateError naming
+// the failed operation.
+func (s *Session) cleanup(datastore string, discard bool) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	var firstErr error
+	if discard {
+		if err := s.exec(ctx, DiscardChanges(), nil); err != nil {
+			firstErr = &CandidateError{Phase: "discard-changes", Err: err}
+		}
+	}
+
+	if err := s.exec(ctx, Unlock(datastore), nil); err != nil && firstErr == nil {
+		firstErr = &CandidateError{Phase: "unlock", Err: err}
+	}
+
+	return firstErr
+}
+
+// ApplyCandidate applies the configuration through the candidate datastore,
+// in the order RFC 6241 recommends: it locks the candidate, loads the config
+// with EditConfig, validates it, commits it, and unlocks the candidate.
+//
+// If any operation after the lock fails, the candidate's changes are
+// discarded, and it is unlocked, before returning a CandidateError naming
+// the failed phase. The cleanup does not use the given context, which may
+// have caused the failure, and any error it returns is reported in the
+// CandidateError's CleanupErr. The server must advertise CapabilityCandidate.
+func (s *Session) ApplyCandidate(ctx context.Context, config interface{}, opts ...ApplyOption) error {
+
+	var o applyOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := s.exec(ctx, Lock(DatastoreCandidate), nil); err != nil {
+		return &CandidateError{Phase: "lock", Err: err}
+	}
+
+	commit := Commit()
+	if o.confirmed {
+		commit = ConfirmedCommit(o.confirmTimeout)
+	}
+
+	phases := []struct {
+		name   string
+		method *Method
+	}{
+		{"edit-config", EditConfig(DatastoreCandidate, config)},
+		{"validate", Validate(DatastoreCandidate)},
+		{"commit", commit},
+	}
+
+	for _, phase := range phases {
+		if phase.name == "validate" && o.skipValidate {
+			continue
+		}
+		if err := s.exec(ctx, phase.method, nil); err != nil {
+			return &CandidateError{
+				Phase:      phase.name,
+				Err:        err,
+				CleanupErr: s.cleanup(DatastoreCandidate, true),
+			}
+		}
+	}
+
+	if err := s.exec(ctx, Unlock(DatastoreCandidate), nil); err != nil {
+		return &CandidateError{Phase: "unlock", Err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package netconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCandidateMethods(t *testing.T) {
+
+	type System struct {
+		XMLName  xml.Name `xml:"system"`
+		HostName string   `xml:"host-name"`
+	}
+
+	tests := []struct {
+		name   string
+		method *Method
+		want   string
+	}{
+		{"Lock", Lock(DatastoreCandidate), `<lock><target><candidate></candidate></target></lock>`},
+		{"Unlock", Unlock(DatastoreCandidate), `<unlock><target><candidate></candidate></target></unlock>`},
+		{"EditConfig", EditConfig(DatastoreCandidate, &System{HostName: "router1"}),
+			`<edit-config><target><candidate></candidate></target><config><system><host-name>router1</host-name></system></config></edit-config>`},
+		{"Validate", Validate(DatastoreCandidate), `<validate><source><candidate></candidate></source></validate>`},
+		{"Commit", Commit(), `<commit></commit>`},
+		{"ConfirmedCommit", ConfirmedCommit(2 * time.Minute), `<commit><confirmed></confirmed><confirm-timeout>120</confirm-timeout></commit>`},
+		{"DiscardChanges", DiscardChanges(), `<discard-changes></discard-changes>`},
+		{"CancelCommit", CancelCommit(""), `<cancel-commit></cancel-commit>`},
+		{"CancelCommitPersistID", CancelCommit("change-42"), `<cancel-commit><persist-id>change-42</persist-id></cancel-commit>`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := MarshalMethod(test.method)
+			if err != nil {
+				t.Fatal(err)
+			} else if !bytes.Contains(got, []byte(test.want)) {
+				t.Errorf("unexpected operation\nwant:\t%q\ngot:\t%q", test.want, got)
+			}
+		})
+	}
+}
+
+// candidateServer returns a test session handler that records the name
+// of every operation it receives, and replies to the failing operation
+// with an rpc-error.
+func candidateServer(ops *[]string, failing string) func(req []byte) []byte {
+	return func(req []byte) []byte {
+
+		var rpc struct {
+			Operation struct {
+				XMLName xml.Name
+			} `xml:",any"`
+		}
+		if err := xml.Unmarshal(req, &rpc); err != nil {
+			panic(err)
+		}
+		op := rpc.Operation.XMLName.Local
+		*ops = append(*ops, op)
+
+		if op == failing {
+			return []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<rpc-error>
+<error-type>application</error-type>
+<error-tag>operation-failed</error-tag>
+<error-severity>error</error-severity>
+</rpc-error>
+</rpc-reply>`)
+		}
+		return []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><ok/></rpc-reply>`)
+	}
+}
+
+func TestSession_ApplyCandidate(t *testing.T) {
+
+	var ops []string
+	session, stop := newTestSession(t, candidateServer(&ops, ""))
+	defer stop()
+
+	if err := session.ApplyCandidate(context.Background(), &struct {
+		XMLName xml.Name `xml:"system"`
+	}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"lock", "edit-config", "validate", "commit", "unlock"}
+	if !reflect.DeepEqual(want, ops) {
+		t.Errorf("unexpected operations\nwant:\t%q\ngot:\t%q", want, ops)
+	}
+}
+
+func TestSession_ApplyCandidate_LockDenied(t *testing.T) {
+
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		return []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<rpc-error>
+<error-type>protocol</error-type>
+<error-tag>lock-denied</error-tag>
+<error-severity>error</error-severity>
+<error-info>
+<session-id>454</session-id>
+</error-info>
+</rpc-error>
+</rpc-reply>`)
+	})
+	defer stop()
+
+	err := session.ApplyCandidate(context.Background(), &struct {
+		XMLName xml.Name `xml:"system"`
+	}{})
+
+	var candidateErr *CandidateError
+	var lockErr *LockDeniedError
+	if !errors.As(err, &candidateErr) {
+		t.Fatalf("unexpected error type:\nwant:\t%T\ngot:\t%T", candidateErr, err)
+	} else if candidateErr.Phase != "lock" {
+		t.Errorf("unexpected phase\nwant:\t%q\ngot:\t%q", "lock", candidateErr.Phase)
+	} else if !errors.As(err, &lockErr) {
+		t.Errorf("unexpected wrapped error type:\nwant:\t%T\ngot:\t%T", lockErr, candidateErr.Err)
+	} else if lockErr.SessionID != 454 {
+		t.Errorf("unexpected lock holder session-id:\nwant:\t%d\ngot:\t%d", 454, lockErr.SessionID)
+	}
+}
+
+func TestSession_ApplyCandidate_Failure(t *testing.T) {
+
+	var ops []string
+	session, stop := newTestSession(t, candidateServer(&ops, "validate"))
+	defer stop()
+
+	err := session.ApplyCandidate(context.Background(), &struct {
+		XMLName xml.Name `xml:"system"`
+	}{}, WithConfirmedCommit(time.Minute))
+
+	var candidateErr *CandidateError
+	if !errors.As(err, &candidateErr) {
+		t.Fatalf("unexpected error type:\nwant:\t%T\ngot:\t%T", candidateErr, err)
+	} else if candidateErr.Phase != "validate" {
+		t.Errorf("unexpected phase\nwant:\t%q\ngot:\t%q", "validate", candidateErr.Phase)
+	} else if _, ok := candidateErr.Err.(*ReplyError); !ok {
+		t.Errorf("unexpected wrapped error type:\nwant:\t%T\ngot:\t%T", &ReplyError{}, candidateErr.Err)
+	}
+
+	want := []string{"lock", "edit-config", "validate", "discard-changes", "unlock"}
+	if !reflect.DeepEqual(want, ops) {
+		t.Errorf("unexpected operations\nwant:\t%q\ngot:\t%q", want, ops)
+	}
+}
+
+func TestSession_ApplyCandidate_ContextDone(t *testing.T) {
+
+	var ops []string
+	session, stop := newTestSession(t, candidateServer(&ops, ""))
+
+	// the context is cancelled while the edit-config is sent, so the
+	// cleanup must either still run, or report why it could not, when
+	// the cancellation closed the session
+	ctx, cancel := context.WithCancel(context.Background())
+	err := session.ApplyCandidate(ctx, &cancelOnMarshal{cancel: cancel})
+
+	// wait for the server, which records the operations
+	stop()
+
+	var candidateErr *CandidateError
+	if !errors.As(err, &candidateErr) {
+		t.Fatalf("unexpected error type:\nwant:\t%T\ngot:\t%T", candidateErr, err)
+	}
+
+	cleanedUp := len(ops) >= 2 && ops[len(ops)-2] == "discard-changes" && ops[len(ops)-1] == "unlock"
+	if !cleanedUp && candidateErr.CleanupErr == nil {
+		t.Errorf("the cleanup neither ran, nor reported an error: %q", ops)
+	}
+}
+
+// cancelOnMarshal cancels a context when it is marshaled.
+type cancelOnMarshal struct {
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnMarshal) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	c.cancel()
+	return e.EncodeElement("", xml.StartElement{Name: xml.Name{Local: "system"}})
+}
@@ -0,0 +1,124 @@
+package netconf
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// Capability is a parsed capability URI, as advertised in a hello
+// message. Both the NETCONF capability form, e.g.
+//
+//	urn:ietf:params:netconf:capability:candidate:1.0
+//
+// and the YANG module form, e.g.
+//
+//	urn:ietf:params:xml:ns:yang:ietf-interfaces?module=ietf-interfaces&revision=2014-05-08
+//
+// are supported.
+type Capability struct {
+	// Base is the capability URI without its version or parameters, e.g.
+	// "urn:ietf:params:netconf:capability:candidate", or the module's
+	// namespace for the YANG module form.
+	Base string
+
+	// Version is the version of a NETCONF capability (e.g. "1.0"),
+	// or the revision parameter of a YANG module.
+	Version string
+
+	// Params are the parameters following the "?", e.g. module,
+	// revision, and features.
+	Params url.Values
+}
+
+// String returns the capability URI.
+func (c Capability) String() string {
+
+	uri := c.Base
+	if c.Version != "" && c.Params.Get("revision") == "" {
+		uri += ":" + c.Version
+	}
+
+	if len(c.Params) != 0 {
+		uri += "?" + c.Params.Encode()
+	}
+
+	return uri
+}
+
+// InvalidCapabilityError is returned when a capability URI can't be parsed.
+type InvalidCapabilityError struct {
+	Capability string // Capability is the invalid URI.
+	Reason     string // Reason describes why it is invalid.
+}
+
+// Error is InvalidCapabilityError's implementation of the error interface.
+func (e *InvalidCapabilityError) Error() string {
+	return fmt.Sprintf("netconf: invalid capability %q: %s", e.Capability, e.Reason)
+}
+
+// netconfCapabilityPrefix prefixes every capability defined by NETCONF,
+// including the base protocol, which end with a version.
+const netconfCapabilityPrefix = "urn:ietf:params:netconf:"
+
+// ParseCapability parses a capability URI. An InvalidCapabilityError is
+// returned if it is empty, contains whitespace, has no scheme, or has
+// malformed parameters.
+func ParseCapability(uri string) (Capability, error) {
+
+	invalid := func(reason string) (Capability, error) {
+		return Capability{}, &InvalidCapabilityError{Capability: uri, Reason: reason}
+	}
+
+	if uri == "" {
+		return invalid("empty URI")
+	} else if strings.IndexFunc(uri, unicode.IsSpace) != -1 {
+		return invalid("contains whitespace")
+	}
+
+	base, query := uri, ""
+	if i := strings.IndexByte(uri, '?'); i != -1 {
+		base, query = uri[:i], uri[i+1:]
+	}
+
+	if strings.IndexByte(base, ':') <= 0 {
+		return invalid("missing scheme")
+	}
+
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return invalid(err.Error())
+	}
+
+	c := Capability{Base: base}
+	if len(params) != 0 {
+		c.Params = params
+	}
+
+	if revision := params.Get("revision"); revision != "" {
+		c.Version = revision
+	} else if strings.HasPrefix(base, netconfCapabilityPrefix) {
+		i := strings.LastIndexByte(base, ':')
+		if version := base[i+1:]; isCapabilityVersion(version) {
+			c.Base, c.Version = base[:i], version
+		} else {
+			return invalid("missing version")
+		}
+	}
+
+	return c, nil
+}
+
+// isCapabilityVersion reports whether s is a version, like "1.0".
+func isCapabilityVersion(s string) bool {
+	if s == "" || s[0] == '.' || s[len(s)-1] == '.' {
+		return false
+	}
+	for _, r := range s {
+		if r != '.' && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package netconf
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseCapability(t *testing.T) {
+
+	tests := []struct {
+		URI  string
+		Want Capability
+	}{
+		{
+			URI: "urn:ietf:params:netconf:base:1.1",
+			Want: Capability{
+				Base:    "urn:ietf:params:netconf:base",
+				Version: "1.1",
+			},
+		},
+		{
+			URI: "urn:ietf:params:netconf:capability:candidate:1.0",
+			Want: Capability{
+				Base:    "urn:ietf:params:netconf:capability:candidate",
+				Version: "1.0",
+			},
+		},
+		{
+			URI: "urn:ietf:params:netconf:capability:with-defaults:1.0?basic-mode=explicit&also-supported=report-all",
+			Want: Capability{
+				Base:    "urn:ietf:params:netconf:capability:with-defaults",
+				Version: "1.0",
+				Params: url.Values{
+					"basic-mode":     {"explicit"},
+					"also-supported": {"report-all"},
+				},
+			},
+		},
+		{
+			URI: "urn:ietf:params:xml:ns:yang:ietf-interfaces?module=ietf-interfaces&revision=2014-05-08&features=if-mib",
+			Want: Capability{
+				Base:    "urn:ietf:params:xml:ns:yang:ietf-interfaces",
+				Version: "2014-05-08",
+				Params: url.Values{
+					"module":   {"ietf-interfaces"},
+					"revision": {"2014-05-08"},
+					"features": {"if-mib"},
+				},
+			},
+		},
+		{
+			URI: "http://cisco.com/ns/yang/Cisco-IOS-XR-ifmgr-cfg?module=Cisco-IOS-XR-ifmgr-cfg",
+			Want: Capability{
+				Base:   "http://cisco.com/ns/yang/Cisco-IOS-XR-ifmgr-cfg",
+				Params: url.Values{"module": {"Cisco-IOS-XR-ifmgr-cfg"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		if got, err := ParseCapability(test.URI); err != nil {
+			t.Errorf("unexpected error parsing %q: %v", test.URI, err)
+		} else if !reflect.DeepEqual(test.Want, got) {
+			t.Errorf("unexpected capability parsed from %q\nwant:\t%+v\ngot:\t%+v", test.URI, test.Want, got)
+		}
+	}
+
+	for _, uri := range []string{
+		"",
+		"candidate",
+		"urn:ietf:params:netconf:base",
+		"urn:ietf:params:netconf:base:1.0 ",
+		"urn:ietf:params:xml:ns:yang:ietf-interfaces?module=%zz",
+	} {
+		if _, err := ParseCapability(uri); err == nil {
+			t.Errorf("expected an error parsing %q", uri)
+		} else if capErr, ok := err.(*InvalidCapabilityError); !ok {
+			t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", capErr, err)
+		}
+	}
+}
+
+func TestCapability_String(t *testing.T) {
+
+	for _, uri := range []string{
+		"urn:ietf:params:netconf:base:1.1",
+		"urn:ietf:params:xml:ns:yang:ietf-interfaces?module=ietf-interfaces&revision=2014-05-08",
+	} {
+		if c, err := ParseCapability(uri); err != nil {
+			t.Errorf("unexpected error parsing %q: %v", uri, err)
+		} else if got := c.String(); uri != got {
+			t.Errorf("unexpected capability string\nwant:\t%q\ngot:\t%q", uri, got)
+		}
+	}
+}
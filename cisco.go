@@ -0,0 +1,27 @@
+package netconf
+
+// CiscoGet returns a Method with a get operation for Cisco IOS-XR
+// devices, which require the subtree filter's content to be in the
+// Cisco YANG model's namespace, e.g.
+// "http://cisco.com/ns/yang/Cisco-IOS-XR-ifmgr-cfg". The filter's
+// root element is placed in the given namespace, so the structs
+// describing it need not declare it.
+//
+// The rpc element itself stays in BaseNamespace, as RFC 6241 requires
+// of every rpc, whatever the data model of its content; only the data
+// nested in the filter is in the Cisco namespace. CiscoGetNS sets the
+// rpc's namespace for the devices that expect otherwise.
+//
+// IOS-XR only accepts one operation per RPC, and its error-path
+// values use namespace prefixes (e.g. "ns1:pbr") declared on the
+// error-path element itself, rather than on the reply.
+func CiscoGet(filter interface{}, namespace string) *Method {
+	return CiscoGetNS(BaseNamespace, filter, namespace)
+}
+
+// CiscoGetNS is like CiscoGet, but the rpc element, and the get
+// operation it contains, are in the given rpcNamespace, like WrapMethodNS.
+func CiscoGetNS(rpcNamespace string, filter interface{}, namespace string) *Method {
+	subtree := SubtreeFilterNS(namespace, filter)
+	return WrapMethodNS(rpcNamespace, &getOperation{Filter: &subtree})
+}
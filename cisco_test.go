@@ -0,0 +1,167 @@
+package netconf
+
+import (
+	"encoding/xml"
+	"fmt"
+	"testing"
+)
+
+func TestCiscoGet(t *testing.T) {
+
+	type InterfaceConfigurations struct {
+		XMLName xml.Name `xml:"interface-configurations"`
+		Name    string   `xml:"interface-configuration>interface-name"`
+	}
+
+	const ifmgrNamespace = "http://cisco.com/ns/yang/Cisco-IOS-XR-ifmgr-cfg"
+
+	method := CiscoGet(&InterfaceConfigurations{Name: "GigabitEthernet0/0/0/0"}, ifmgrNamespace)
+
+	b, err := Marshal(method)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := fmt.Sprintf(`<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="%s"><get><filter type="subtree"><interface-configurations xmlns="%s"><interface-configuration><interface-name>GigabitEthernet0/0/0/0</interface-name></interface-configuration></interface-configurations></filter></get></rpc>]]>]]>
+`, method.Attr[0].Value, ifmgrNamespace)
+
+	if got := string(b); want != got {
+		t.Errorf("unexpected bytes encoded\nwant:\t%q\ngot:\t%q", want, got)
+	}
+
+	const rpcNamespace = "urn:example:rpc"
+
+	method = CiscoGetNS(rpcNamespace, &InterfaceConfigurations{Name: "GigabitEthernet0/0/0/0"}, ifmgrNamespace)
+
+	b, err = Marshal(method)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = fmt.Sprintf(`<rpc xmlns="%s" message-id="%s"><get><filter type="subtree"><interface-configurations xmlns="%s"><interface-configuration><interface-name>GigabitEthernet0/0/0/0</interface-name></interface-configuration></interface-configurations></filter></get></rpc>]]>]]>
+`, rpcNamespace, method.Attr[0].Value, ifmgrNamespace)
+
+	if got := string(b); want != got {
+		t.Errorf("unexpected bytes encoded\nwant:\t%q\ngot:\t%q", want, got)
+	}
+
+	type NamespacedConfigurations struct {
+		XMLName xml.Name `xml:"http://cisco.com/ns/yang/Cisco-IOS-XR-pbr-cfg policy-maps"`
+	}
+
+	b, err = xml.Marshal(SubtreeFilterNS(ifmgrNamespace, &NamespacedConfigurations{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the payload's own namespace takes priority
+	wantFilter := `<filter type="subtree"><policy-maps xmlns="http://cisco.com/ns/yang/Cisco-IOS-XR-pbr-cfg"></policy-maps></filter>`
+	if got := string(b); wantFilter != got {
+		t.Errorf("unexpected filter encoded\nwant:\t%q\ngot:\t%q", wantFilter, got)
+	}
+}
+
+func TestEncoder_EncodeNamespaces(t *testing.T) {
+
+	type PBR struct {
+		XMLName       xml.Name `xml:"ns1:pbr"`
+		ServicePolicy string   `xml:"ns1:service-policy>ns1:input"`
+	}
+
+	type InterfaceConfiguration struct {
+		XMLName xml.Name `xml:"ns2:interface-configuration"`
+		Name    string   `xml:"ns2:interface-name"`
+		PBR     PBR
+	}
+
+	type EditConfig struct {
+		XMLName xml.Name                 `xml:"edit-config"`
+		Target  struct{}                 `xml:"target>candidate"`
+		Config  []InterfaceConfiguration `xml:"config>ns2:interface-configurations>ns2:interface-configuration"`
+	}
+
+	method := WrapMethodID("101", &EditConfig{
+		Config: []InterfaceConfiguration{{
+			Name: "GigabitEthernet0/0/0/0",
+			PBR:  PBR{ServicePolicy: "redirect"},
+		}},
+	})
+	method.Namespaces = map[string]string{
+		"ns2": "http://cisco.com/ns/yang/Cisco-IOS-XR-ifmgr-cfg",
+		"ns1": "http://cisco.com/ns/yang/Cisco-IOS-XR-pbr-cfg",
+	}
+
+	b, err := Marshal(method)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="101" xmlns:ns1="http://cisco.com/ns/yang/Cisco-IOS-XR-pbr-cfg" xmlns:ns2="http://cisco.com/ns/yang/Cisco-IOS-XR-ifmgr-cfg"><edit-config><target><candidate></candidate></target><config><ns2:interface-configurations><ns2:interface-configuration><ns2:interface-name>GigabitEthernet0/0/0/0</ns2:interface-name><ns1:pbr><ns1:service-policy><ns1:input>redirect</ns1:input></ns1:service-policy></ns1:pbr></ns2:interface-configuration></ns2:interface-configurations></config></edit-config></rpc>]]>]]>
+`
+
+	if got := string(b); want != got {
+		t.Errorf("unexpected bytes encoded\nwant:\t%q\ngot:\t%q", want, got)
+	} else if len(method.Attr) != 1 {
+		t.Errorf("argument's attributes were modified: %v", method.Attr)
+	}
+}
+
+func TestEditConfigNS(t *testing.T) {
+
+	type InterfaceConfiguration struct {
+		XMLName     xml.Name `xml:"ns1:interface-configuration"`
+		Active      string   `xml:"ns1:active"`
+		Name        string   `xml:"ns1:interface-name"`
+		Description string   `xml:"ns1:description"`
+		Mask        string   `xml:"ns2:ipv4-network>ns2:addresses>ns2:primary>ns2:netmask"`
+	}
+
+	type InterfaceConfigurations struct {
+		XMLName        xml.Name `xml:"ns1:interface-configurations"`
+		Configurations []InterfaceConfiguration
+	}
+
+	method := EditConfigNS(DatastoreCandidate, &InterfaceConfigurations{
+		Configurations: []InterfaceConfiguration{{
+			Active:      "act",
+			Name:        "GigabitEthernet0/0/0/0",
+			Description: "uplink",
+			Mask:        "255.255.255.0",
+		}},
+	}, map[string]string{
+		"ns1": "http://cisco.com/ns/yang/Cisco-IOS-XR-ifmgr-cfg",
+		"ns2": "http://cisco.com/ns/yang/Cisco-IOS-XR-ipv4-io-cfg",
+	})
+
+	b, err := xml.Marshal(method.Method[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the prefixes are declared once, on the operation, as IOS-XR expects
+	want := `<edit-config xmlns:ns1="http://cisco.com/ns/yang/Cisco-IOS-XR-ifmgr-cfg" xmlns:ns2="http://cisco.com/ns/yang/Cisco-IOS-XR-ipv4-io-cfg"><target><candidate></candidate></target><config><ns1:interface-configurations><ns1:interface-configuration><ns1:active>act</ns1:active><ns1:interface-name>GigabitEthernet0/0/0/0</ns1:interface-name><ns1:description>uplink</ns1:description><ns2:ipv4-network><ns2:addresses><ns2:primary><ns2:netmask>255.255.255.0</ns2:netmask></ns2:primary></ns2:addresses></ns2:ipv4-network></ns1:interface-configuration></ns1:interface-configurations></config></edit-config>`
+
+	if got := string(b); want != got {
+		t.Fatalf("unexpected bytes encoded\nwant:\t%q\ngot:\t%q", want, got)
+	}
+
+	// decoding resolves the prefixes, proving they are declared
+	var decoded struct {
+		Configurations []struct {
+			Name string `xml:"http://cisco.com/ns/yang/Cisco-IOS-XR-ifmgr-cfg interface-name"`
+			Mask string `xml:"http://cisco.com/ns/yang/Cisco-IOS-XR-ipv4-io-cfg ipv4-network>addresses>primary>netmask"`
+		} `xml:"config>interface-configurations>interface-configuration"`
+	}
+	if err := xml.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	} else if len(decoded.Configurations) != 1 {
+		t.Fatalf("unexpected interface configurations decoded: %+v", decoded)
+	}
+
+	if got := decoded.Configurations[0].Name; got != "GigabitEthernet0/0/0/0" {
+		t.Errorf("unexpected interface name decoded\nwant:\t%q\ngot:\t%q", "GigabitEthernet0/0/0/0", got)
+	}
+	if got := decoded.Configurations[0].Mask; got != "255.255.255.0" {
+		t.Errorf("unexpected netmask decoded\nwant:\t%q\ngot:\t%q", "255.255.255.0", got)
+	}
+}
@@ -0,0 +1,146 @@
+package netconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+)
+
+// CopyConfigFrom replaces the target datastore (e.g. DatastoreCandidate)
+// with the configuration read from src, using the copy-config operation.
+// The configuration is streamed into the RPC token by token, so it is
+// never held in memory entirely, which matters for large configurations.
+//
+// The src must contain the children of the config element, e.g. a
+// configuration saved from the data element of a get-config reply. Its
+// elements are sent exactly as written, along with their namespace and
+// prefix declarations, but comments, processing instructions, and
+// directives are not sent.
+//
+// The target is not checked against the server's capabilities, e.g. running
+// requires CapabilityWritableRunning, so ValidateTarget can be used first.
+//
+// If src is not well-formed XML, the RPC is left partially written, so
+// the session is poisoned, and must be closed. The session is also
+// closed if the context is done before the reply is read.
+func (s *Session) CopyConfigFrom(ctx context.Context, target string, src io.Reader) error {
+
+	targetElem := xml.StartElement{Name: xml.Name{Local: "target"}}
+	datastore := xml.StartElement{Name: xml.Name{Local: target}}
+
+	return s.execConfigFrom(ctx, "copy-config", []xml.Token{
+		targetElem, datastore, datastore.End(), targetElem.End(),
+	}, src)
+}
+
+// execConfigFrom sends an RPC with the given operation, whose parameters
+// are the given tokens followed by a source holding the configuration
+// streamed from src, and decodes its reply.
+func (s *Session) execConfigFrom(ctx context.Context, operation string, params []xml.Token, src io.Reader) error {
+
+	enc := s.NewEncoder()
+	method := WrapMethod()
+
+	err := enc.doContext(ctx, func() error {
+
+		if err := enc.poisonedError(); err != nil {
+			return err
+		}
+
+		if err := enc.encodeConfigFrom(method, operation, params, src); err != nil {
+			enc.poison(err)
+			return err
+		}
+
+		return enc.WriteSep()
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.decodeContext(ctx, &Reply{})
+}
+
+// encodeConfigFrom encodes the tokens of an RPC with the given operation
+// and parameters, followed by a source holding the configuration read
+// from src.
+func (e *Encoder) encodeConfigFrom(method *Method, operation string, params []xml.Token, src io.Reader) error {
+
+	rpc := xml.StartElement{Name: method.XMLName, Attr: method.Attr}
+	op := xml.StartElement{Name: xml.Name{Local: operation}}
+
+	toks := append([]xml.Token{rpc, op}, params...)
+	toks = append(toks,
+		xml.StartElement{Name: xml.Name{Local: "source"}},
+		xml.StartElement{Name: xml.Name{Local: "config"}},
+	)
+	for _, tok := range toks {
+		if err := e.EncodeToken(tok); err != nil {
+			return err
+		}
+	}
+
+	// the configuration is copied as written, rather than re-encoded,
+	// because the xml.Encoder would drop its prefix declarations, which
+	// values like identityrefs, and attributes like nc:operation, need
+	if err := e.Encoder.Flush(); err != nil {
+		return err
+	}
+
+	var raw bytes.Buffer
+	d := xml.NewDecoder(io.TeeReader(src, &raw))
+
+	var open []xml.Name // every open element, to check they are closed
+	var offset int64    // offset of the end of the previous token
+
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		next := d.InputOffset()
+		text := raw.Next(int(next - offset))
+		offset = next
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			open = append(open, t.Name)
+		case xml.EndElement:
+			if len(open) == 0 || open[len(open)-1] != t.Name {
+				return &xml.SyntaxError{Msg: "unexpected end element </" + rawName(t.Name) + ">"}
+			}
+			open = open[:len(open)-1]
+		case xml.Comment, xml.ProcInst, xml.Directive:
+			continue
+		}
+
+		if _, err := e.bufWriter.Write(text); err != nil {
+			return err
+		}
+	}
+
+	if len(open) != 0 {
+		return &xml.SyntaxError{Msg: "unexpected EOF, missing </" + rawName(open[len(open)-1]) + ">"}
+	}
+
+	for _, name := range []string{"config", "source", operation} {
+		if err := e.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}}); err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(rpc.End())
+}
+
+// rawName returns the name of an element returned by RawToken,
+// whose Space holds its prefix, as written.
+func rawName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
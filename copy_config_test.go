@@ -0,0 +1,66 @@
+package netconf
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSession_CopyConfigFrom(t *testing.T) {
+
+	var gotReq []byte
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		gotReq = req
+		return []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><ok/></rpc-reply>`)
+	})
+	defer stop()
+
+	config := `<!-- saved config -->
+<interfaces xmlns="urn:ietf:params:xml:ns:yang:ietf-interfaces"><interface><name>eth0</name></interface></interfaces>`
+
+	if err := session.CopyConfigFrom(context.Background(), DatastoreCandidate, strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []byte(`<copy-config><target><candidate></candidate></target><source><config>
+<interfaces xmlns="urn:ietf:params:xml:ns:yang:ietf-interfaces"><interface><name>eth0</name></interface></interfaces></config></source></copy-config></rpc>`)
+	if !bytes.HasPrefix(gotReq, []byte(`<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="`)) {
+		t.Errorf("request does not begin with an rpc element: %q", gotReq)
+	} else if !bytes.HasSuffix(gotReq, want) {
+		t.Errorf("unexpected request received by server\nwant:\t%q\ngot:\t%q", want, gotReq)
+	}
+
+	// prefixes must stay declared, for identityref values, and for
+	// attributes like nc:operation, and an empty default namespace
+	// must not be inherited
+	prefixed := `<interfaces xmlns="urn:ietf:params:xml:ns:yang:ietf-interfaces" xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+		`<interface nc:operation="replace"><name>eth0</name>` +
+		`<type xmlns:ianaift="urn:ietf:params:xml:ns:yang:iana-if-type">ianaift:ethernetCsmacd</type>` +
+		`<description xmlns="">uplink &amp; backup</description><enabled/></interface></interfaces>`
+
+	if err := session.CopyConfigFrom(context.Background(), DatastoreCandidate, strings.NewReader(prefixed)); err != nil {
+		t.Fatal(err)
+	}
+
+	want = []byte(`<source><config>` + prefixed + `</config></source></copy-config></rpc>`)
+	if !bytes.HasSuffix(gotReq, want) {
+		t.Errorf("unexpected request received by server\nwant:\t%q\ngot:\t%q", want, gotReq)
+	}
+
+	err := NewEncoder(io.Discard).encodeConfigFrom(WrapMethod(), "copy-config", nil, strings.NewReader(`<interfaces></config>`))
+	if err == nil {
+		t.Error("expected an error streaming a config with a mismatched end element")
+	}
+
+	err = session.CopyConfigFrom(context.Background(), DatastoreCandidate, strings.NewReader(`<interfaces>`))
+	if err == nil {
+		t.Fatal("expected an error streaming a truncated config")
+	}
+
+	err = session.CopyConfigFrom(context.Background(), DatastoreCandidate, strings.NewReader(config))
+	if poisonedErr, ok := err.(*SessionPoisonedError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", poisonedErr, err)
+	}
+}
@@ -3,65 +3,60 @@ package netconf
 import (
 	"context"
 	"strconv"
+	"sync/atomic"
 )
 
 // GlobalCounter keeps a running count of every NETCONF RPC. It is incremented
-// by the DefaultXMLAttr and DefaultRPCMethodWrapper functions.
+// by the WrapMethod and WrapMethodNS functions, which use its new value as
+// the RPC's message-id.
 //
 // GlobalCounter is safe for client applications to access, use, and increment.
-var GlobalCounter = NewUintCounterContext(context.Background())
+var GlobalCounter = new(Uint)
 
 // Uint is a 64-bit unsigned integer variable that satisfies the expvar.Var interface.
+// It is safe for concurrent use, and its zero value is ready to use.
 type Uint struct {
-	readChan chan uint64
-	setChan  chan uint64
-	addChan  chan uint64
-	val      uint64
+	val atomic.Uint64
 }
 
-// NewUintCounterContext allocates the new unsigned integer counter
-// with resources that can only be cancelled by a context.
-func NewUintCounterContext(ctx context.Context) *Uint {
-
-	var u Uint
-
-	u.readChan = make(chan uint64)
-	u.setChan = make(chan uint64)
-	u.addChan = make(chan uint64)
-
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case u.readChan <- u.val:
-			case val := <-u.setChan:
-				u.val = val
-			case delta := <-u.addChan:
-				u.val += delta
-			}
-		}
-	}()
-
-	return &u
+// NewUintCounterContext allocates a new unsigned integer counter.
+//
+// Deprecated: Uint no longer holds any resources, so there is nothing to
+// release when the context is cancelled, or the returned stop function is
+// called, and the counter stays usable after either. Use new(Uint), or the
+// zero value, instead.
+func NewUintCounterContext(ctx context.Context) (*Uint, context.CancelFunc) {
+	return new(Uint), func() {}
 }
 
 // Value returns the current value of the underlying uint64.
 func (v *Uint) Value() uint64 {
-	return <-v.readChan
+	return v.val.Load()
 }
 
 // String converts the underlying uint64 to its base 10 string representation.
 func (v *Uint) String() string {
-	return strconv.FormatUint(<-v.readChan, 10)
+	return strconv.FormatUint(v.val.Load(), 10)
 }
 
 // Add add the given delta argument to the underlying uint64 value.
 func (v *Uint) Add(delta uint64) {
-	v.addChan <- delta
+	v.val.Add(delta)
+}
+
+// Incr adds one to the underlying uint64, and returns the new value,
+// in a single atomic operation. Unlike calling Add and then Value,
+// concurrent callers never observe the same value.
+func (v *Uint) Incr() uint64 {
+	return v.val.Add(1)
 }
 
 // Set assigns the given value argument to the underlying uint64.
 func (v *Uint) Set(value uint64) {
-	v.setChan <- value
+	v.val.Store(value)
+}
+
+// Reset sets the underlying uint64 to zero.
+func (v *Uint) Reset() {
+	v.val.Store(0)
 }
@@ -0,0 +1,66 @@
+package netconf
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestUint_Reset(t *testing.T) {
+
+	var u Uint
+
+	u.Add(5)
+	if got := u.Value(); got != 5 {
+		t.Errorf("unexpected counter value\nwant:\t%d\ngot:\t%d", 5, got)
+	}
+
+	u.Reset()
+	if got := u.Value(); got != 0 {
+		t.Errorf("unexpected counter value after reset\nwant:\t%d\ngot:\t%d", 0, got)
+	}
+}
+
+func TestNewUintCounterContext_Cancel(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	u, stop := NewUintCounterContext(ctx)
+	u.Add(1)
+
+	cancel()
+	stop()
+
+	// the counter holds no resources, so it keeps
+	// counting after the context is cancelled
+	u.Add(1)
+	if got := u.Incr(); got != 3 {
+		t.Errorf("unexpected counter value after cancel\nwant:\t%d\ngot:\t%d", 3, got)
+	}
+}
+
+func TestWrapMethod_UniqueMessageIDs(t *testing.T) {
+
+	const goroutines, perGoroutine = 8, 1000
+
+	ids := make(chan string, goroutines*perGoroutine)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				ids <- WrapMethod().Attr[0].Value
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[string]bool, goroutines*perGoroutine)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("duplicate message-id %q", id)
+		}
+		seen[id] = true
+	}
+}
@@ -0,0 +1,144 @@
+package netconf
+
+import (
+	"context"
+	"encoding/xml"
+	"strings"
+)
+
+// ExecMap sends the method, and decodes the data in its reply into a generic
+// map, for ad-hoc tooling that doesn't want to model the data with structs.
+//
+// The map holds the children of the reply's data element, or the elements
+// of the reply itself when the server omits the data element, as many vendor
+// RPCs do. Elements are keyed by their local name, and their value is:
+//
+//   - the element's text, with surrounding whitespace trimmed, when it has
+//     neither attributes nor child elements
+//   - otherwise, a map[string]interface{} holding its child elements, its
+//     attributes keyed by their local name prefixed with "@", and its
+//     non-whitespace text keyed by "#text"
+//
+// Repeated elements with the same name are collected in an []interface{},
+// in document order. Namespaces are discarded.
+//
+// Errors in the reply are returned like Decode does, alongside the data
+// decoded before them. The session is closed if the context is done before
+// the reply is read.
+func (s *Session) ExecMap(ctx context.Context, method *Method) (map[string]interface{}, error) {
+
+	data := dataMap{}
+
+	err := s.exec(ctx, method, &data)
+	switch err.(type) {
+	case nil, *ReplyError, *LockDeniedError, *MultiError:
+		return data, err
+	}
+
+	return nil, err
+}
+
+// dataMap collects the data of a reply into a generic map.
+type dataMap map[string]interface{}
+
+// UnmarshalXML is called for every element of the reply that is not an
+// rpc-error or ok element. The children of a data element are added to
+// the map, and any other element is added itself.
+func (m *dataMap) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+
+	if start.Name.Local != "data" {
+		v, err := decodeMapValue(d, start)
+		if err != nil {
+			return err
+		}
+		addMapValue(*m, start.Name.Local, v)
+		return nil
+	}
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			v, err := decodeMapValue(d, t)
+			if err != nil {
+				return err
+			}
+			addMapValue(*m, t.Name.Local, v)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// decodeMapValue decodes the element starting with start into a string,
+// or a map[string]interface{}, as described by ExecMap.
+func decodeMapValue(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+
+	var (
+		text     strings.Builder
+		children map[string]interface{}
+	)
+
+	if len(start.Attr) != 0 {
+		children = make(map[string]interface{}, len(start.Attr))
+		for _, attr := range start.Attr {
+			// namespace declarations are not data
+			if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+				continue
+			}
+			children["@"+attr.Name.Local] = attr.Value
+		}
+	}
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			v, err := decodeMapValue(d, t)
+			if err != nil {
+				return nil, err
+			}
+			if children == nil {
+				children = make(map[string]interface{})
+			}
+			addMapValue(children, t.Name.Local, v)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			trimmed := strings.TrimSpace(text.String())
+			if len(children) == 0 {
+				return trimmed, nil
+			}
+			if trimmed != "" {
+				children["#text"] = trimmed
+			}
+			return children, nil
+		}
+	}
+}
+
+// addMapValue adds the value to the map under the given key, collecting
+// the values of repeated keys in an []interface{}.
+func addMapValue(m map[string]interface{}, key string, v interface{}) {
+
+	existing, ok := m[key]
+	if !ok {
+		m[key] = v
+		return
+	}
+
+	if values, ok := existing.([]interface{}); ok {
+		m[key] = append(values, v)
+		return
+	}
+
+	m[key] = []interface{}{existing, v}
+}
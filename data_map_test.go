@@ -0,0 +1,87 @@
+package netconf
+
+import (
+	"context"
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestSession_ExecMap(t *testing.T) {
+
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		return []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<data>
+<interfaces xmlns="urn:ietf:params:xml:ns:yang:ietf-interfaces">
+<interface>
+<name>ge-0/0/0</name>
+<enabled>true</enabled>
+</interface>
+<interface>
+<name>ge-0/0/1</name>
+<description lang="en">uplink</description>
+</interface>
+</interfaces>
+<hostname>router1</hostname>
+</data>
+</rpc-reply>`)
+	})
+	defer stop()
+
+	got, err := session.ExecMap(context.Background(), Get(SubtreeFilter(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"interfaces": map[string]interface{}{
+			"interface": []interface{}{
+				map[string]interface{}{
+					"name":    "ge-0/0/0",
+					"enabled": "true",
+				},
+				map[string]interface{}{
+					"name": "ge-0/0/1",
+					"description": map[string]interface{}{
+						"@lang": "en",
+						"#text": "uplink",
+					},
+				},
+			},
+		},
+		"hostname": "router1",
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected map\nwant:\t%#v\ngot:\t%#v", want, got)
+	}
+}
+
+func TestSession_ExecMap_NoData(t *testing.T) {
+
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		return []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<software-information>
+<host-name>router1</host-name>
+</software-information>
+</rpc-reply>`)
+	})
+	defer stop()
+
+	got, err := session.ExecMap(context.Background(), WrapMethod(&struct {
+		XMLName xml.Name `xml:"get-software-information"`
+	}{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"software-information": map[string]interface{}{
+			"host-name": "router1",
+		},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected map\nwant:\t%#v\ngot:\t%#v", want, got)
+	}
+}
@@ -0,0 +1,89 @@
+package netconf
+
+import (
+	"fmt"
+)
+
+const (
+	// DatastoreRunning is the running configuration datastore, which
+	// every server supports, but only those with the :writable-running
+	// capability allow to edit directly.
+	DatastoreRunning = "running"
+
+	// DatastoreCandidate is the candidate configuration datastore,
+	// which requires the :candidate capability.
+	DatastoreCandidate = "candidate"
+
+	// DatastoreStartup is the startup configuration datastore,
+	// which requires the :startup capability.
+	DatastoreStartup = "startup"
+)
+
+const (
+	// CapabilityCandidate is the capability a server advertises when
+	// it supports the candidate datastore.
+	CapabilityCandidate = "urn:ietf:params:netconf:capability:candidate:1.0"
+
+	// CapabilityStartup is the capability a server advertises when
+	// it supports the startup datastore.
+	CapabilityStartup = "urn:ietf:params:netconf:capability:startup:1.0"
+
+	// CapabilityWritableRunning is the capability a server advertises
+	// when edit-config and copy-config can target the running datastore.
+	CapabilityWritableRunning = "urn:ietf:params:netconf:capability:writable-running:1.0"
+)
+
+// datastoreCapabilities maps each optional datastore
+// to the capability it requires.
+var datastoreCapabilities = map[string]string{
+	DatastoreCandidate: CapabilityCandidate,
+	DatastoreStartup:   CapabilityStartup,
+}
+
+// UnsupportedDatastoreError is returned when an operation targets
+// a datastore the server did not advertise the capability for.
+type UnsupportedDatastoreError struct {
+	Datastore  string // Datastore is the unsupported datastore, e.g. candidate.
+	Capability string // Capability is the missing capability.
+}
+
+// Error is UnsupportedDatastoreError's implementation of the error interface.
+func (e *UnsupportedDatastoreError) Error() string {
+	return fmt.Sprintf("netconf: server does not support the %s datastore without capability %s",
+		e.Datastore, e.Capability)
+}
+
+// ValidateDatastore checks the server with the given hello message
+// supports the datastore, so a doomed RPC targeting it is not sent.
+// An UnsupportedDatastoreError is returned if the capability the
+// datastore requires was not advertised. Datastores without a
+// well-known capability, like running, are always valid, since they
+// can always be read; use ValidateTarget for datastores being written.
+// A nil hello message advertises no capability.
+func ValidateDatastore(serverHello *HelloMessage, datastore string) error {
+
+	capability, ok := datastoreCapabilities[datastore]
+	if ok && !serverHello.HasCapability(capability) {
+		return &UnsupportedDatastoreError{
+			Datastore:  datastore,
+			Capability: capability,
+		}
+	}
+
+	return nil
+}
+
+// ValidateTarget is like ValidateDatastore, but the datastore is the target
+// of an operation writing it, like edit-config or copy-config, so running is
+// only valid if the server advertises CapabilityWritableRunning.
+func ValidateTarget(serverHello *HelloMessage, datastore string) error {
+
+	if datastore == DatastoreRunning && !serverHello.HasCapability(CapabilityWritableRunning) {
+		return &UnsupportedDatastoreError{
+			Datastore:  datastore,
+			Capability: CapabilityWritableRunning,
+		}
+	}
+
+	return ValidateDatastore(serverHello, datastore)
+}
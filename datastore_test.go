@@ -0,0 +1,54 @@
+package netconf
+
+import (
+	"testing"
+)
+
+func TestValidateDatastore(t *testing.T) {
+
+	hello := HelloMessage{Capabilities: []string{
+		"urn:ietf:params:netconf:base:1.0",
+		CapabilityStartup,
+	}}
+
+	for _, datastore := range []string{DatastoreRunning, DatastoreStartup} {
+		if err := ValidateDatastore(&hello, datastore); err != nil {
+			t.Errorf("unexpected error validating %s datastore: %v", datastore, err)
+		}
+	}
+
+	err := ValidateDatastore(&hello, DatastoreCandidate)
+	if dsErr, ok := err.(*UnsupportedDatastoreError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", dsErr, err)
+	} else if dsErr.Datastore != DatastoreCandidate {
+		t.Errorf("unexpected datastore:\nwant:\t%q\ngot:\t%q", DatastoreCandidate, dsErr.Datastore)
+	} else if dsErr.Capability != CapabilityCandidate {
+		t.Errorf("unexpected capability:\nwant:\t%q\ngot:\t%q", CapabilityCandidate, dsErr.Capability)
+	}
+}
+
+func TestValidateTarget(t *testing.T) {
+
+	hello := HelloMessage{Capabilities: []string{
+		"urn:ietf:params:netconf:base:1.0",
+		CapabilityCandidate,
+	}}
+
+	if err := ValidateTarget(&hello, DatastoreCandidate); err != nil {
+		t.Errorf("unexpected error validating candidate datastore: %v", err)
+	}
+
+	for _, serverHello := range []*HelloMessage{&hello, nil} {
+		err := ValidateTarget(serverHello, DatastoreRunning)
+		if dsErr, ok := err.(*UnsupportedDatastoreError); !ok {
+			t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", dsErr, err)
+		} else if dsErr.Capability != CapabilityWritableRunning {
+			t.Errorf("unexpected capability:\nwant:\t%q\ngot:\t%q", CapabilityWritableRunning, dsErr.Capability)
+		}
+	}
+
+	hello.Capabilities = append(hello.Capabilities, CapabilityWritableRunning)
+	if err := ValidateTarget(&hello, DatastoreRunning); err != nil {
+		t.Errorf("unexpected error validating running datastore: %v", err)
+	}
+}
@@ -4,7 +4,12 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/xml"
+	"fmt"
 	"io"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"unicode"
 )
 
 // Reply models the structure of a NETCONF reply.
@@ -12,10 +17,81 @@ import (
 // decode the outer rpc-reply tags.
 type Reply struct {
 	XMLName xml.Name     `xml:"rpc-reply"`
-	Attr    []xml.Attr   `xml:",attr"`
+	Attr    []xml.Attr   `xml:",any,attr"`
 	Ok      *struct{}    `xml:"ok"`
 	Error   []ReplyError `xml:"rpc-error"`
 	Data    interface{}  `xml:",any"`
+
+	// NestedError holds the rpc-error elements found within the reply's
+	// data, which some devices use to report partial failures. It is
+	// only populated by a Decoder configured with ScanNestedErrors.
+	NestedError []ReplyError `xml:"-"`
+}
+
+// AllErrors returns every ReplyError in the reply, those that are
+// children of the rpc-reply element followed by the nested ones.
+func (r *Reply) AllErrors() []ReplyError {
+	if len(r.NestedError) == 0 {
+		return r.Error
+	}
+	return append(append([]ReplyError(nil), r.Error...), r.NestedError...)
+}
+
+// Success reports whether the server processed the RPC successfully,
+// meaning the reply holds no ReplyError with an error severity, even if
+// it is nested (see Decoder.ScanNestedErrors). Warnings don't count as
+// failures. An ok element is not required, because some servers reply
+// to a successful edit-config with an empty rpc-reply, so Success is
+// true for an empty reply, unlike checking Ok for nil.
+func (r *Reply) Success() bool {
+	for _, err := range r.AllErrors() {
+		if err.Severity == ErrorSeverityError {
+			return false
+		}
+	}
+	return true
+}
+
+// RawReply models a NETCONF reply whose content is kept exactly as the
+// server sent it, for troubleshooting tools that need to show what a
+// device returned. Errors in the reply are also decoded, so Decode
+// still returns them.
+type RawReply struct {
+	XMLName  xml.Name     `xml:"rpc-reply"`
+	Attr     []xml.Attr   `xml:",any,attr"`
+	Error    []ReplyError `xml:"rpc-error"`
+	InnerXML []byte       `xml:",innerxml"` // InnerXML is the raw content of the rpc-reply element.
+}
+
+// RawElement is an element of a reply's data, whose content is kept
+// exactly as it was received.
+type RawElement struct {
+	XMLName  xml.Name
+	Attr     []xml.Attr `xml:",any,attr"`
+	InnerXML []byte     `xml:",innerxml"`
+}
+
+// MessageID returns the reply's message-id attribute, which matches
+// the message-id of the RPC it replies to, or an empty string if the
+// reply has none.
+func (r *Reply) MessageID() string {
+	for _, attr := range r.Attr {
+		if attr.Name.Space == "" && attr.Name.Local == "message-id" {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// Namespace returns the reply's default namespace, which is usually
+// BaseNamespace.
+func (r *Reply) Namespace() string {
+	for _, attr := range r.Attr {
+		if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			return attr.Value
+		}
+	}
+	return r.XMLName.Space
 }
 
 // Decoder embeds an xml.Decoder, but overrides Decode
@@ -23,7 +99,11 @@ type Reply struct {
 // to decode NETCONF RPC replies.
 type Decoder struct {
 	*xml.Decoder
-	bufReader *bufio.Reader
+	bufReader      *bufio.Reader
+	failOnWarnings bool
+	keepComments   bool
+	scanNested     bool
+	received       *uint64 // number of replies received by a Session, if any
 }
 
 // NewDecoder buffers the given io.Reader, and wraps it
@@ -38,9 +118,108 @@ func NewDecoder(r io.Reader) *Decoder {
 	return &d
 }
 
+// NewDecoderSize is like NewDecoder, but the given io.Reader is
+// buffered with at least size bytes, instead of the bufio default.
+// A larger buffer (e.g. 64KB) reduces the number of reads from the
+// session when decoding large replies, like those of a get operation.
+func NewDecoderSize(r io.Reader, size int) *Decoder {
+
+	var d Decoder
+
+	d.bufReader = bufio.NewReaderSize(r, size)
+	d.Decoder = xml.NewDecoder(d.bufReader)
+
+	return &d
+}
+
+// NewDecoderWithCharset is like NewDecoder, but replies declaring a
+// character encoding other than UTF-8 in their XML declaration (e.g.
+// encoding="ISO-8859-1") are converted to UTF-8 with the given function,
+// whose signature matches xml.Decoder's CharsetReader field, e.g.
+// golang.org/x/net/html/charset.NewReaderLabel.
+//
+// The input given to cr is read one byte at a time, so the converter
+// never reads past the end of a reply, and the message separator is
+// still found by SkipSep. Once a reply declares a charset, every later
+// reply decoded by the Decoder is converted, so a Decoder must not be
+// shared across replies declaring different charsets.
+func NewDecoderWithCharset(r io.Reader, cr func(charset string, input io.Reader) (io.Reader, error)) *Decoder {
+
+	d := NewDecoder(r)
+
+	var current string
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		if current != "" {
+			// the input is already converted by a previous reply's declaration
+			if !strings.EqualFold(charset, current) {
+				return nil, fmt.Errorf("netconf: charset changed from %q to %q", current, charset)
+			}
+			return input, nil
+		}
+		converted, err := cr(charset, &byteAtATimeReader{d.bufReader})
+		if err == nil {
+			current = charset
+		}
+		return converted, err
+	}
+
+	return d
+}
+
+// byteAtATimeReader reads a single byte per call to Read, so readers
+// wrapping it can't consume bytes beyond those they need.
+type byteAtATimeReader struct {
+	io.ByteReader
+}
+
+// Read implements the io.Reader interface.
+func (r *byteAtATimeReader) Read(p []byte) (int, error) {
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	p[0] = b
+
+	return 1, nil
+}
+
+// FailOnWarnings configures Decode to return ReplyErrors with a
+// warning severity, in addition to those with an error severity.
+// By default, warnings are not returned as errors.
+func (d *Decoder) FailOnWarnings(fail bool) {
+	d.failOnWarnings = fail
+}
+
+// KeepComments configures Decode to preserve the comments and processing
+// instructions in the InnerXML of a RawReply, which some devices use for
+// diagnostics. By default, they are removed, just like they are ignored
+// when decoding into any other type.
+func (d *Decoder) KeepComments(keep bool) {
+	d.keepComments = keep
+}
+
+// ScanNestedErrors configures Decode to also search the reply's data for
+// rpc-error elements, at any depth, which some vendors embed alongside
+// partial results instead of reporting them as children of rpc-reply.
+// They are returned just like any other ReplyError, and are available
+// in the NestedError field of a *Reply. By default, the data is not
+// searched, because doing so keeps a copy of the reply in memory.
+func (d *Decoder) ScanNestedErrors(scan bool) {
+	d.scanNested = scan
+}
+
 // DecodeHello handles hello/capabilities messages sent by
 // the NETCONF server. It's a special decode case since the
 // closing tags are named "hello" rather than "rpc-reply".
+//
+// Hello messages always end with the "]]>]]>" separator, even when both
+// peers advertise base:1.1, because chunked framing is only used after
+// the hello exchange completes (RFC 6242 section 4.1).
 func (d *Decoder) DecodeHello(h *HelloMessage) error {
 
 	if err := d.Decoder.Decode(h); err != nil {
@@ -52,21 +231,43 @@ func (d *Decoder) DecodeHello(h *HelloMessage) error {
 	return nil
 }
 
-// Decode wraps the interface{} parameter in a Reply object
-// to capture all of the RPC Reply content. It also searches
-// for errors in the Reply, and returns the first ReplyError
-// found.
-// as a standard error interface.
+// Decode unmarshals a single NETCONF RPC reply message into
+// the given interface{}, which is wrapped in a Reply to capture
+// all of the RPC Reply content. It also searches for errors in
+// the Reply, and returns the ReplyError found, or a MultiError
+// if the Reply contains more than one, as a standard error. A single
+// error tagged lock-denied is returned as a LockDeniedError wrapping
+// the ReplyError, with the session holding the lock.
 //
+// A full RPC Reply can be obtained by passing a *Reply, whose Data
+// field holds the value the data is decoded into. It is populated
+// entirely, including every ReplyError, even when an error is returned.
+// A *RawReply keeps the reply's content exactly as it was received.
 //
-// unmarshals a single NETCONF RPC reply message into
-// the given interface{}.
+// When v is a pointer to a slice of structs, every element of the reply's
+// data is decoded into a new item of the slice. If the struct has an
+// XMLName field with a name in its tag, elements with other names are
+// skipped, rather than failing the decode.
 //
-// A full RPC Reply can be obtained
 // Parsing XML as a stream of tokens is still possible using
-// the embedded xml.Decoder. However, Done should be called
-// finished to discard the NETCONF message separator.
+// the embedded xml.Decoder. However, SkipSep should be called
+// when finished to discard the NETCONF message separator.
 func (d *Decoder) Decode(v interface{}) error {
+	return d.countReply(d.decode(v))
+}
+
+// decode implements Decode.
+func (d *Decoder) decode(v interface{}) error {
+
+	if rawReply, ok := v.(*RawReply); ok {
+		if err := d.Decoder.Decode(rawReply); err != nil {
+			return err
+		}
+		if !d.keepComments {
+			rawReply.InnerXML = stripComments(rawReply.InnerXML)
+		}
+		return d.replyError(rawReply.Error)
+	}
 
 	reply, ok := v.(*Reply)
 	if !ok {
@@ -76,28 +277,306 @@ func (d *Decoder) Decode(v interface{}) error {
 		}
 	}
 
+	// every data root is appended to a slice, skipping those
+	// whose name doesn't match the slice's element type
+	if collector, ok := newSliceCollector(reply.Data); ok {
+		data := reply.Data
+		reply.Data = collector
+		defer func() { reply.Data = data }()
+	}
+
+	if d.scanNested {
+		return d.decodeNested(reply)
+	}
+
 	if err := d.Decoder.Decode(reply); err != nil {
 		return err
 	}
 
-	// TODO: Consider returning here if the caller provided a Reply
+	return d.replyError(reply.Error)
+}
 
-	for i, err := range reply.Error {
-		if err.Severity == ErrorSeverityError {
-			return &reply.Error[i]
+// sliceCollector appends every data root of a reply to a slice of
+// structs, skipping the roots whose name doesn't match the XMLName
+// tag of the struct, if it has one.
+type sliceCollector struct {
+	slice reflect.Value // slice is the slice the pointer given to Decode points to.
+	name  xml.Name      // name is the XMLName tag of the slice's element type.
+}
+
+// newSliceCollector returns a sliceCollector if v is a pointer
+// to a slice of structs, or of pointers to structs.
+func newSliceCollector(v interface{}) (*sliceCollector, bool) {
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return nil, false
+	}
+
+	elemType := rv.Elem().Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	collector := sliceCollector{slice: rv.Elem()}
+	if f, ok := elemType.FieldByName("XMLName"); ok && f.Type == reflect.TypeOf(xml.Name{}) {
+		name := strings.Split(f.Tag.Get("xml"), ",")[0]
+		if i := strings.LastIndexByte(name, ' '); i != -1 {
+			collector.name = xml.Name{Space: name[:i], Local: name[i+1:]}
+		} else {
+			collector.name = xml.Name{Local: name}
 		}
 	}
 
+	return &collector, true
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface. It is
+// called for every data root of the reply.
+func (sc *sliceCollector) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+
+	if (sc.name.Local != "" && sc.name.Local != start.Name.Local) ||
+		(sc.name.Space != "" && sc.name.Space != start.Name.Space) {
+		return d.Skip()
+	}
+
+	elemType := sc.slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+
+	elem := reflect.New(elemType)
+	if err := d.DecodeElement(elem.Interface(), &start); err != nil {
+		return err
+	}
+
+	if !isPtr {
+		elem = elem.Elem()
+	}
+	sc.slice.Set(reflect.Append(sc.slice, elem))
+
 	return nil
 }
 
+// decodeNested is like Decode, but the reply's content is also searched
+// for nested rpc-error elements.
+func (d *Decoder) decodeNested(reply *Reply) error {
+
+	scanned := struct {
+		XMLName xml.Name `xml:"rpc-reply"`
+		*Reply
+		InnerXML []byte `xml:",innerxml"`
+	}{Reply: reply}
+
+	if err := d.Decoder.Decode(&scanned); err != nil {
+		return err
+	}
+	reply.XMLName = scanned.XMLName
+
+	nested, err := nestedErrors(scanned.InnerXML)
+	if err != nil {
+		return err
+	}
+	reply.NestedError = nested
+
+	return d.replyError(reply.AllErrors())
+}
+
+// nestedErrors returns the rpc-error elements in the given content of
+// an rpc-reply, except its direct children, which are decoded already.
+func nestedErrors(b []byte) ([]ReplyError, error) {
+
+	var errs []ReplyError
+	dec := xml.NewDecoder(bytes.NewReader(b))
+
+	for depth := 0; ; {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return errs, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if depth == 0 || tok.Name.Local != "rpc-error" {
+				depth++
+				continue
+			}
+			var replyErr ReplyError
+			if err = dec.DecodeElement(&replyErr, &tok); err != nil {
+				return nil, err
+			}
+			errs = append(errs, replyErr)
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// stripComments returns the XML without its comments and processing
+// instructions, leaving every other byte intact. If the XML can't be
+// tokenized, everything after the failure is left as is.
+func stripComments(b []byte) []byte {
+
+	d := xml.NewDecoder(bytes.NewReader(b))
+
+	var (
+		stripped []byte
+		kept     int64 // offset of the first byte not yet copied
+	)
+
+	for {
+		begin := d.InputOffset()
+		tok, err := d.RawToken()
+		if err != nil {
+			break
+		}
+
+		switch tok.(type) {
+		case xml.Comment, xml.ProcInst:
+			stripped = append(stripped, b[kept:begin]...)
+			kept = d.InputOffset()
+		}
+	}
+
+	if kept == 0 {
+		return b
+	}
+
+	return append(stripped, b[kept:]...)
+}
+
+// countReply counts a reply received by the Session, unless the
+// given error shows it was not read entirely, and returns the error.
+func (d *Decoder) countReply(err error) error {
+	switch err.(type) {
+	case nil, *ReplyError, *LockDeniedError, *MultiError:
+		if d.received != nil {
+			atomic.AddUint64(d.received, 1)
+		}
+	}
+	return err
+}
+
+// replyError returns the ReplyError in the given slice with an error
+// severity (or warning severity if failOnWarnings is set), wrapped in a
+// LockDeniedError if it is tagged lock-denied, a MultiError if there is
+// more than one, or nil if there are none.
+func (d *Decoder) replyError(replyErrs []ReplyError) error {
+
+	var errs []*ReplyError
+	for i, err := range replyErrs {
+		if err.Severity == ErrorSeverityError ||
+			(d.failOnWarnings && err.Severity == ErrorSeverityWarning) {
+			errs = append(errs, &replyErrs[i])
+		}
+	}
+
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		if errs[0].Tag == ErrorTagLockDenied {
+			return &LockDeniedError{SessionID: errs[0].Info.SessionID, Err: errs[0]}
+		}
+		return errs[0]
+	default:
+		return &MultiError{errs: errs}
+	}
+}
+
+// DecodeEach incrementally decodes a single NETCONF RPC reply,
+// calling fn for every element matching the given path, so large
+// replies can be processed without decoding them entirely into memory.
+//
+// The path is relative to the rpc-reply element, and uses the same
+// "a>b>c" syntax as encoding/xml struct tags, e.g.
+// "lldp-neighbors-information>lldp-neighbor-information".
+//
+// The fn argument must consume the entire element it is given,
+// usually with DecodeElement or Skip. Any error it returns is
+// returned immediately. Errors in the reply are returned after
+// the reply is completely read, just like Decode.
+func (d *Decoder) DecodeEach(path string, fn func(d *xml.Decoder, start xml.StartElement) error) error {
+
+	pathNames := strings.Split(path, ">")
+
+	var (
+		inReply   bool
+		names     []string
+		replyErrs []ReplyError
+	)
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if !inReply {
+				if t.Name.Local != "rpc-reply" {
+					return fmt.Errorf("netconf: expected element rpc-reply, found %s", t.Name.Local)
+				}
+				inReply = true
+				continue
+			}
+
+			if len(names) == 0 && t.Name.Local == "rpc-error" {
+				var replyErr ReplyError
+				if err := d.DecodeElement(&replyErr, &t); err != nil {
+					return err
+				}
+				replyErrs = append(replyErrs, replyErr)
+				continue
+			}
+
+			names = append(names, t.Name.Local)
+			if equalNames(names, pathNames) {
+				if err := fn(d.Decoder, t); err != nil {
+					return err
+				}
+				names = names[:len(names)-1]
+			}
+		case xml.EndElement:
+			if len(names) == 0 {
+				return d.countReply(d.replyError(replyErrs))
+			}
+			names = names[:len(names)-1]
+		}
+	}
+}
+
+// equalNames reports whether both slices contain the same names.
+func equalNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // messageSeparatorBytes is a micro-optimization that eliminates the
 // need to create a new byte slice every time we search for the NETCONF
 // message message separator.
 var messageSeparatorBytes = []byte(MessageSeparator)
 
 // SkipSep discarding everything from the underlying buffer until it
-// encounters a NETCONF message separator, or an error.
+// encounters a NETCONF message separator, or an error. Whitespace
+// following the separator, like the blank lines some servers send
+// between messages, is also discarded if it has already been read
+// from the net.Conn, so the next message, and its XML prolog, can be
+// decoded by the same Decoder.
 //
 // Since the separator is explicitly designed to be invalid XML,
 // failure to discard it before decoding will cause the standard
@@ -112,19 +591,87 @@ var messageSeparatorBytes = []byte(MessageSeparator)
 // Most uses will call Decode, which calls SkipSep internally.
 func (d *Decoder) SkipSep() error {
 
-	for {
-		if s, err := d.bufReader.ReadSlice('\n'); err != nil && err != bufio.ErrBufferFull {
+	// the separator is found when the last bytes read match it
+	var window [len(MessageSeparator)]byte
+	for read := 0; read < len(window) || !bytes.Equal(window[:], messageSeparatorBytes); read++ {
+		b, err := d.bufReader.ReadByte()
+		if err != nil {
 			return err
-		} else if bytes.Equal(bytes.TrimSpace(s), messageSeparatorBytes) {
-			break
+		}
+		copy(window[:], window[1:])
+		window[len(window)-1] = b
+	}
+
+	return d.skipBufferedSpace()
+}
+
+// skipBufferedSpace discards whitespace, but only whitespace that is
+// already buffered, so it never blocks waiting for the next message.
+func (d *Decoder) skipBufferedSpace() error {
+
+	for d.bufReader.Buffered() > 0 {
+		b, err := d.bufReader.ReadByte()
+		if err != nil {
+			return err
+		}
+		if !unicode.IsSpace(rune(b)) {
+			return d.bufReader.UnreadByte()
 		}
 	}
 
 	return nil
 }
 
+// DecodeRaw reads one message, and returns its exact bytes, excluding
+// the message separator and surrounding whitespace, without decoding
+// it. It is useful for proxies that forward replies verbatim, and for
+// recording test fixtures.
+//
+// An io.EOF error is returned if the stream ends before a message
+// begins, and io.ErrUnexpectedEOF if it ends within one.
+func (d *Decoder) DecodeRaw() ([]byte, error) {
+
+	var msg []byte
+	for !bytes.HasSuffix(msg, messageSeparatorBytes) {
+		b, err := d.bufReader.ReadSlice('>')
+		msg = append(msg, b...)
+		if err == io.EOF && len(bytes.TrimSpace(msg)) != 0 {
+			return nil, io.ErrUnexpectedEOF
+		} else if err != nil && err != bufio.ErrBufferFull {
+			return nil, err
+		}
+	}
+
+	if err := d.skipBufferedSpace(); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSpace(msg[:len(msg)-len(messageSeparatorBytes)]), d.countReply(nil)
+}
+
 // Unmarshal maps the NETCONF RPC reply XML into the given argument,
 // discarding the terminating message separator.
 func Unmarshal(data []byte, v interface{}) error {
 	return NewDecoder(bytes.NewReader(data)).Decode(v)
 }
+
+// UnmarshalReply is like Unmarshal, but it returns the entire reply, so
+// Ok, every ReplyError, and the attributes of saved replies can be
+// inspected offline, just like ExecReply. The reply's Data field is a
+// *[]RawElement, holding every element of the reply's data.
+//
+// The errors in the reply are also returned as a ReplyError, or a
+// MultiError, alongside the reply. The reply is nil only if the data
+// is not a well-formed reply.
+func UnmarshalReply(data []byte) (*Reply, error) {
+
+	reply := Reply{Data: &[]RawElement{}}
+
+	err := Unmarshal(data, &reply)
+	switch err.(type) {
+	case nil, *ReplyError, *LockDeniedError, *MultiError:
+		return &reply, err
+	}
+
+	return nil, err
+}
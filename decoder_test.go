@@ -3,9 +3,12 @@ package netconf
 import (
 	"bytes"
 	"encoding/xml"
+	"fmt"
+	"io"
 	"reflect"
 	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestDecoder_DecodeHello(t *testing.T) {
@@ -138,6 +141,32 @@ func TestDecoder_DecodeHello(t *testing.T) {
 	}
 }
 
+func TestDecoder_DecodeHello_Concatenated(t *testing.T) {
+
+	const hello = `<?xml version="1.0" encoding="UTF-8"?>
+<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<capabilities>
+<capability>urn:ietf:params:netconf:base:1.0</capability>
+</capabilities>
+<session-id>%d</session-id>
+</hello>`
+
+	// the second hello begins on the same line as the first separator,
+	// and the third follows a separator split from its hello by blank lines
+	dec := NewDecoder(strings.NewReader(fmt.Sprintf(hello, 1) + "]]>]]>" +
+		fmt.Sprintf(hello, 2) + "\n]]>]]>\n\n\n" +
+		fmt.Sprintf(hello, 3) + "]]>]]>\n"))
+
+	for want := uint(1); want <= 3; want++ {
+		var h HelloMessage
+		if err := dec.DecodeHello(&h); err != nil {
+			t.Fatalf("decoding hello %d: %v", want, err)
+		} else if h.SessionID != want {
+			t.Errorf("unexpected session-id:\nwant:\t%d\ngot:\t%d", want, h.SessionID)
+		}
+	}
+}
+
 func BenchmarkDecoder_DecodeHello(b *testing.B) {
 
 	helloBytes := []byte(`<?xml version="1.0" encoding="UTF-8"?>
@@ -312,6 +341,50 @@ func TestReply_Unmarshal(t *testing.T) {
 	}
 }
 
+func TestReply_UnmarshalSlice(t *testing.T) {
+
+	type Neighbor struct {
+		XMLName     xml.Name `xml:"lldp-neighbor-information"`
+		LocalPortID string   `xml:"lldp-local-port-id"`
+	}
+
+	replyBytes := []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<lldp-neighbor-information>
+<lldp-local-port-id>ge-0/0/7</lldp-local-port-id>
+</lldp-neighbor-information>
+<cli><banner></banner></cli>
+<lldp-neighbor-information>
+<lldp-local-port-id>ge-0/0/8</lldp-local-port-id>
+</lldp-neighbor-information>
+</rpc-reply>
+]]>]]>
+`)
+
+	var neighbors []Neighbor
+	if err := Unmarshal(replyBytes, &neighbors); err != nil {
+		t.Fatal(err)
+	} else if len(neighbors) != 2 {
+		t.Fatalf("unexpected number of neighbors:\nwant:\t%d\ngot:\t%d", 2, len(neighbors))
+	}
+
+	for i, want := range []string{"ge-0/0/7", "ge-0/0/8"} {
+		if got := neighbors[i].LocalPortID; want != got {
+			t.Errorf("unexpected port of neighbor %d:\nwant:\t%q\ngot:\t%q", i, want, got)
+		}
+	}
+
+	// a slice of pointers in a full Reply is filled the same way
+	var neighborPtrs []*Neighbor
+	reply := Reply{Data: &neighborPtrs}
+	if err := Unmarshal(replyBytes, &reply); err != nil {
+		t.Fatal(err)
+	} else if len(neighborPtrs) != 2 || neighborPtrs[1].LocalPortID != "ge-0/0/8" {
+		t.Errorf("unexpected neighbors: %+v", neighborPtrs)
+	} else if reply.Data != &neighborPtrs {
+		t.Error("the reply's Data field was not restored")
+	}
+}
+
 func TestReply_UnmarshalOk(t *testing.T) {
 	okReplyBytes1 := []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/15.1X49/junos">
 <ok/>
@@ -336,5 +409,402 @@ func TestReply_UnmarshalOk(t *testing.T) {
 		t.Error(err)
 	} else if okReplyObj2.Ok != nil {
 		t.Errorf("unexpected reply ok value:\nwant:\t%t\ngot:\t%t", false, okReplyObj2.Ok != nil)
+	} else if !okReplyObj2.Success() {
+		t.Errorf("unexpected reply success value:\nwant:\t%t\ngot:\t%t", true, okReplyObj2.Success())
+	}
+
+	failedReplyBytes := []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<rpc-error>
+<error-type>application</error-type>
+<error-tag>operation-failed</error-tag>
+<error-severity>error</error-severity>
+</rpc-error>
+</rpc-reply>
+]]>]]>
+`)
+
+	var failedReplyObj Reply
+	if err := Unmarshal(failedReplyBytes, &failedReplyObj); err == nil {
+		t.Error("expected an error for a failed reply")
+	} else if failedReplyObj.Success() {
+		t.Errorf("unexpected reply success value:\nwant:\t%t\ngot:\t%t", false, failedReplyObj.Success())
+	}
+}
+
+func TestUnmarshalReply(t *testing.T) {
+
+	replyBytes := []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="7">
+<rpc-error>
+<error-type>application</error-type>
+<error-tag>invalid-value</error-tag>
+<error-severity>error</error-severity>
+</rpc-error>
+<interface-information><name>ge-0/0/0</name></interface-information>
+</rpc-reply>
+]]>]]>
+`)
+
+	reply, err := UnmarshalReply(replyBytes)
+	if _, ok := err.(*ReplyError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", &ReplyError{}, err)
+	}
+	if reply == nil {
+		t.Fatal("reply is nil")
+	}
+
+	if got := reply.MessageID(); got != "7" {
+		t.Errorf("unexpected message-id:\nwant:\t%q\ngot:\t%q", "7", got)
+	}
+	if len(reply.Error) != 1 {
+		t.Errorf("unexpected number of errors:\nwant:\t%d\ngot:\t%d", 1, len(reply.Error))
+	}
+
+	data := *reply.Data.(*[]RawElement)
+	if len(data) != 1 || data[0].XMLName.Local != "interface-information" {
+		t.Errorf("unexpected data: %+v", data)
+	}
+
+	if reply, err = UnmarshalReply([]byte(`<hello/>`)); err == nil || reply != nil {
+		t.Errorf("expected only an error for a malformed reply, got %v, %v", reply, err)
+	}
+}
+
+func TestDecoder_FailOnWarnings(t *testing.T) {
+
+	warningReplyBytes := []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="102">
+<rpc-error>
+<error-type>application</error-type>
+<error-tag>operation-failed</error-tag>
+<error-severity>warning</error-severity>
+<error-message>statement has no effect</error-message>
+</rpc-error>
+<ok/>
+</rpc-reply>
+]]>]]>
+`)
+
+	var reply1 Reply
+	if err := NewDecoder(bytes.NewReader(warningReplyBytes)).Decode(&reply1); err != nil {
+		t.Errorf("unexpected error decoding warning by default: %v", err)
+	}
+
+	dec := NewDecoder(bytes.NewReader(warningReplyBytes))
+	dec.FailOnWarnings(true)
+
+	var reply2 Reply
+	if err := dec.Decode(&reply2); err == nil {
+		t.Error("expected warning to be returned as an error")
+	} else if replyErr, ok := err.(*ReplyError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", replyErr, err)
+	} else if replyErr.Severity != ErrorSeverityWarning {
+		t.Errorf("unexpected error severity:\nwant:\t%q\ngot:\t%q",
+			ErrorSeverityWarning, replyErr.Severity)
+	}
+}
+
+func TestDecoder_ScanNestedErrors(t *testing.T) {
+
+	nestedReplyBytes := []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="103">
+<load-configuration-results>
+<rpc-error>
+<error-type>protocol</error-type>
+<error-tag>operation-failed</error-tag>
+<error-severity>error</error-severity>
+<error-message>syntax error</error-message>
+</rpc-error>
+<load-success/>
+</load-configuration-results>
+</rpc-reply>
+]]>]]>
+`)
+
+	type Results struct {
+		XMLName xml.Name  `xml:"load-configuration-results"`
+		Success *struct{} `xml:"load-success"`
+	}
+
+	var results1 Results
+	if err := NewDecoder(bytes.NewReader(nestedReplyBytes)).Decode(&results1); err != nil {
+		t.Errorf("unexpected error decoding nested error by default: %v", err)
+	}
+
+	dec := NewDecoder(bytes.NewReader(nestedReplyBytes))
+	dec.ScanNestedErrors(true)
+
+	var results2 Results
+	reply := Reply{Data: &results2}
+	if err := dec.Decode(&reply); err == nil {
+		t.Error("expected nested rpc-error to be returned as an error")
+	} else if replyErr, ok := err.(*ReplyError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", replyErr, err)
+	} else if replyErr.Message != "syntax error" {
+		t.Errorf("unexpected error message:\nwant:\t%q\ngot:\t%q", "syntax error", replyErr.Message)
+	}
+
+	if results2.Success == nil {
+		t.Error("data was not decoded alongside the nested error")
+	}
+	if len(reply.Error) != 0 || len(reply.AllErrors()) != 1 {
+		t.Errorf("unexpected number of errors:\nwant:\t%d top-level, %d in all\ngot:\t%d top-level, %d in all",
+			0, 1, len(reply.Error), len(reply.AllErrors()))
+	}
+	if got := reply.MessageID(); got != "103" {
+		t.Errorf("unexpected message-id:\nwant:\t%q\ngot:\t%q", "103", got)
+	}
+}
+
+func TestDecoder_DecodeEach(t *testing.T) {
+
+	lldpNbrsRPCReplyBytes := []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/15.1X49/junos">
+<lldp-neighbors-information junos:style="brief">
+<lldp-neighbor-information>
+<lldp-local-port-id>ge-0/0/7</lldp-local-port-id>
+<lldp-remote-system-name>EX2200C2</lldp-remote-system-name>
+</lldp-neighbor-information>
+<lldp-neighbor-information>
+<lldp-local-port-id>ge-0/0/8</lldp-local-port-id>
+<lldp-remote-system-name>EX2200C3</lldp-remote-system-name>
+</lldp-neighbor-information>
+</lldp-neighbors-information>
+</rpc-reply>
+]]>]]>
+`)
+
+	type Neighbor struct {
+		LocalPortID      string `xml:"lldp-local-port-id,omitempty"`
+		RemoteSystemName string `xml:"lldp-remote-system-name,omitempty"`
+	}
+
+	want := []Neighbor{
+		{LocalPortID: "ge-0/0/7", RemoteSystemName: "EX2200C2"},
+		{LocalPortID: "ge-0/0/8", RemoteSystemName: "EX2200C3"},
+	}
+
+	var got []Neighbor
+	err := NewDecoder(bytes.NewReader(lldpNbrsRPCReplyBytes)).DecodeEach(
+		"lldp-neighbors-information>lldp-neighbor-information",
+		func(d *xml.Decoder, start xml.StartElement) error {
+			var nbr Neighbor
+			if err := d.DecodeElement(&nbr, &start); err != nil {
+				return err
+			}
+			got = append(got, nbr)
+			return nil
+		})
+
+	if err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected neighbors decoded:\nwant:\t%v\ngot:\t%v", want, got)
+	}
+}
+
+func BenchmarkNewDecoderSize(b *testing.B) {
+
+	var buf bytes.Buffer
+	buf.WriteString(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><interface-information>`)
+	for buf.Len() < 4<<20 {
+		buf.WriteString(`<physical-interface><name>ge-0/0/0</name><oper-status>up</oper-status></physical-interface>`)
+	}
+	buf.WriteString(`</interface-information></rpc-reply>]]>]]>`)
+	replyBytes := buf.Bytes()
+
+	type InterfaceInformation struct {
+		Names []string `xml:"physical-interface>name"`
+	}
+
+	for _, size := range []int{4096, 64 << 10} {
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			b.SetBytes(int64(len(replyBytes)))
+			r := bytes.NewReader(replyBytes)
+			for i := 0; i < b.N; i++ {
+				r.Reset(replyBytes)
+				var ifaceInfo InterfaceInformation
+				if err := NewDecoderSize(r, size).Decode(&ifaceInfo); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestDecoder_Decode_RawReply(t *testing.T) {
+
+	const reply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="101">
+<output>
+<!-- diagnostic: chassis cooling degraded -->
+Hostname: srx240
+</output>
+</rpc-reply>
+]]>]]>
+`
+
+	var rawReply RawReply
+	if err := NewDecoder(strings.NewReader(reply)).Decode(&rawReply); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `
+<output>
+
+Hostname: srx240
+</output>
+`
+	if got := string(rawReply.InnerXML); want != got {
+		t.Errorf("unexpected inner XML\nwant:\t%q\ngot:\t%q", want, got)
+	}
+
+	dec := NewDecoder(strings.NewReader(reply))
+	dec.KeepComments(true)
+
+	rawReply = RawReply{}
+	if err := dec.Decode(&rawReply); err != nil {
+		t.Fatal(err)
+	}
+
+	want = `
+<output>
+<!-- diagnostic: chassis cooling degraded -->
+Hostname: srx240
+</output>
+`
+	if got := string(rawReply.InnerXML); want != got {
+		t.Errorf("unexpected inner XML\nwant:\t%q\ngot:\t%q", want, got)
+	}
+}
+
+func TestDecoder_DecodeRaw(t *testing.T) {
+
+	const first = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1"><ok/></rpc-reply>`
+	const second = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="2">
+<data><![CDATA[]]>]]></data>
+</rpc-reply>`
+
+	dec := NewDecoder(strings.NewReader(first + "\n]]>]]>\n" + second + "]]>]]>\n"))
+
+	for i, want := range []string{first, second[:strings.Index(second, "]]>]]>")]} {
+		if got, err := dec.DecodeRaw(); err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		} else if want != string(got) {
+			t.Errorf("message %d: unexpected raw message\nwant:\t%q\ngot:\t%q", i, want, got)
+		}
+	}
+
+	dec = NewDecoder(strings.NewReader(first + "\n]]>]]>\n"))
+	if _, err := dec.DecodeRaw(); err != nil {
+		t.Fatal(err)
+	} else if _, err := dec.DecodeRaw(); err != io.EOF {
+		t.Errorf("unexpected error after the last message:\nwant:\t%v\ngot:\t%v", io.EOF, err)
+	}
+
+	dec = NewDecoder(strings.NewReader(first))
+	if _, err := dec.DecodeRaw(); err != io.ErrUnexpectedEOF {
+		t.Errorf("unexpected error for a truncated message:\nwant:\t%v\ngot:\t%v", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestDecoder_Prolog(t *testing.T) {
+
+	const prolog = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+	const hello = `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><session-id>1</session-id></hello>]]>]]>` + "\n"
+	const reply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data><name>test</name></data></rpc-reply>]]>]]>` + "\n"
+
+	tests := []struct {
+		name    string
+		prologs []bool
+	}{
+		{"never", []bool{false, false, false}},
+		{"once", []bool{true, false, false}},
+		{"every message", []bool{true, true, true}},
+		{"replies only", []bool{false, true, true}},
+		{"alternating", []bool{true, false, true}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			var stream strings.Builder
+			for i, hasProlog := range test.prologs {
+				if hasProlog {
+					stream.WriteString(prolog)
+				}
+				if i == 0 {
+					stream.WriteString(hello)
+				} else {
+					stream.WriteString(reply)
+				}
+			}
+
+			dec := NewDecoder(strings.NewReader(stream.String()))
+
+			var h HelloMessage
+			if err := dec.DecodeHello(&h); err != nil {
+				t.Fatal(err)
+			} else if h.SessionID != 1 {
+				t.Errorf("unexpected session-id\nwant:\t%d\ngot:\t%d", 1, h.SessionID)
+			}
+
+			for i := 1; i < len(test.prologs); i++ {
+				var data struct {
+					Name string `xml:"name"`
+				}
+				if err := dec.Decode(&data); err != nil {
+					t.Fatalf("reply %d: %v", i, err)
+				} else if err = dec.SkipSep(); err != nil {
+					t.Fatalf("reply %d: %v", i, err)
+				} else if data.Name != "test" {
+					t.Errorf("reply %d: unexpected data\nwant:\t%q\ngot:\t%q", i, "test", data.Name)
+				}
+			}
+		})
+	}
+}
+
+// latin1Reader converts ISO-8859-1 to UTF-8, reading as much of its
+// input as it can, like the converters in golang.org/x/text.
+type latin1Reader struct {
+	r io.Reader
+}
+
+func (lr *latin1Reader) Read(p []byte) (int, error) {
+	buf := make([]byte, len(p)/utf8.UTFMax+1)
+	n, err := lr.r.Read(buf)
+	var out bytes.Buffer
+	for _, b := range buf[:n] {
+		out.WriteRune(rune(b))
+	}
+	return copy(p, out.Bytes()), err
+}
+
+func TestNewDecoderWithCharset(t *testing.T) {
+
+	const reply = "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n" +
+		`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data><description>caf` + "\xe9" + `</description></data></rpc-reply>]]>]]>` + "\n"
+
+	var gotCharset string
+	dec := NewDecoderWithCharset(strings.NewReader(reply+reply), func(charset string, input io.Reader) (io.Reader, error) {
+		gotCharset = charset
+		return &latin1Reader{r: input}, nil
+	})
+
+	for i := 0; i < 2; i++ {
+		var data struct {
+			Description string `xml:"description"`
+		}
+		if err := dec.Decode(&data); err != nil {
+			t.Fatalf("reply %d: %v", i, err)
+		} else if err = dec.SkipSep(); err != nil {
+			t.Fatalf("reply %d: %v", i, err)
+		} else if data.Description != "café" {
+			t.Errorf("reply %d: unexpected description\nwant:\t%q\ngot:\t%q", i, "café", data.Description)
+		}
+	}
+
+	if gotCharset != "ISO-8859-1" {
+		t.Errorf("unexpected charset\nwant:\t%q\ngot:\t%q", "ISO-8859-1", gotCharset)
+	}
+
+	if err := NewDecoder(strings.NewReader(reply)).Decode(&struct{}{}); err == nil {
+		t.Error("expected an error decoding ISO-8859-1 without a CharsetReader")
 	}
 }
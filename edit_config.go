@@ -0,0 +1,152 @@
+package netconf
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+const (
+	// DefaultOperationMerge merges the configuration with the existing
+	// one, which is the server's default.
+	DefaultOperationMerge = "merge"
+
+	// DefaultOperationReplace replaces the existing configuration.
+	DefaultOperationReplace = "replace"
+
+	// DefaultOperationNone leaves the existing configuration unchanged,
+	// unless an element of the configuration sets its own operation.
+	DefaultOperationNone = "none"
+
+	// TestOptionTestThenSet validates the configuration before applying
+	// it, which requires CapabilityValidate.
+	TestOptionTestThenSet = "test-then-set"
+
+	// TestOptionSet applies the configuration without validating it.
+	TestOptionSet = "set"
+
+	// TestOptionTestOnly validates the configuration without applying
+	// it, which requires CapabilityValidate.
+	TestOptionTestOnly = "test-only"
+
+	// ErrorOptionStopOnError stops at the first error, which is the
+	// server's default.
+	ErrorOptionStopOnError = "stop-on-error"
+
+	// ErrorOptionContinueOnError applies as much of the configuration
+	// as possible, despite errors.
+	ErrorOptionContinueOnError = "continue-on-error"
+
+	// ErrorOptionRollbackOnError restores the previous configuration
+	// after an error, which requires CapabilityRollbackOnError.
+	ErrorOptionRollbackOnError = "rollback-on-error"
+
+	// CapabilityRollbackOnError is the capability a server advertises
+	// when it supports ErrorOptionRollbackOnError.
+	CapabilityRollbackOnError = "urn:ietf:params:netconf:capability:rollback-on-error:1.0"
+)
+
+// editConfigOperation models the edit-config operation.
+type editConfigOperation struct {
+	XMLName          xml.Name       `xml:"edit-config"`
+	Attr             []xml.Attr     `xml:",attr"`
+	Target           datastoreParam `xml:"target"`
+	DefaultOperation string         `xml:"default-operation,omitempty"`
+	TestOption       string         `xml:"test-option,omitempty"`
+	ErrorOption      string         `xml:"error-option,omitempty"`
+	Config           editConfig
+}
+
+// editConfig models the config parameter of the edit-config operation.
+type editConfig struct {
+	XMLName xml.Name    `xml:"config"`
+	Payload interface{} // Payload is the configuration. It must marshal to one or more elements.
+}
+
+// EditOptions holds the optional parameters of the edit-config operation.
+// Empty options are omitted, leaving the server's defaults in effect.
+type EditOptions struct {
+	DefaultOperation string // DefaultOperation is e.g. DefaultOperationReplace.
+	TestOption       string // TestOption is e.g. TestOptionTestThenSet.
+	ErrorOption      string // ErrorOption is e.g. ErrorOptionRollbackOnError.
+
+	// Namespaces maps prefixes to namespaces, which are declared on the
+	// edit-config element, like EditConfigNS does.
+	Namespaces map[string]string
+}
+
+// UnsupportedOptionError is returned when an edit-config option requires
+// a capability the server did not advertise.
+type UnsupportedOptionError struct {
+	Option     string // Option is the parameter, e.g. test-option.
+	Value      string // Value is the unsupported value, e.g. test-only.
+	Capability string // Capability is the missing capability.
+}
+
+// Error is UnsupportedOptionError's implementation of the error interface.
+func (e *UnsupportedOptionError) Error() string {
+	return fmt.Sprintf("netconf: server does not support %s %s without capability %s",
+		e.Option, e.Value, e.Capability)
+}
+
+// EditConfig returns a Method that loads the given configuration into
+// the target datastore (e.g. DatastoreCandidate), merging it with the
+// existing configuration. The config must marshal to one or more XML
+// elements (e.g. a struct with an XMLName field), which are placed in
+// the config element.
+func EditConfig(target string, config interface{}) *Method {
+	return EditConfigNS(target, config, nil)
+}
+
+// EditConfigNS is like EditConfig, but the given prefixes are declared
+// with xmlns:prefix attributes on the edit-config element, so the config's
+// elements can be qualified by a prefix in their struct tags, e.g.
+// "ns1:interface-configurations", like the payloads Cisco devices expect.
+// Unlike Method.Namespaces, the declarations are part of the operation,
+// so they are kept when it is wrapped in a different Method.
+func EditConfigNS(target string, config interface{}, namespaces map[string]string) *Method {
+	return WrapMethod(&editConfigOperation{
+		Attr:   namespaceAttrs(namespaces),
+		Target: datastoreParam(target),
+		Config: editConfig{Payload: config},
+	})
+}
+
+// EditConfigWithOptions is like EditConfig, but the edit-config operation
+// includes the given options. The options, and the target datastore, are
+// first checked against the capabilities in the server's hello message, the
+// target like ValidateTarget, so an UnsupportedOptionError, or an
+// UnsupportedDatastoreError, is returned instead of sending an RPC the
+// server would reject.
+func EditConfigWithOptions(serverHello *HelloMessage, target string, config interface{}, opts EditOptions) (*Method, error) {
+
+	if err := ValidateTarget(serverHello, target); err != nil {
+		return nil, err
+	}
+
+	checks := []struct {
+		option, value, requires, capability string
+	}{
+		{"test-option", opts.TestOption, TestOptionTestThenSet, CapabilityValidate},
+		{"test-option", opts.TestOption, TestOptionTestOnly, CapabilityValidate},
+		{"error-option", opts.ErrorOption, ErrorOptionRollbackOnError, CapabilityRollbackOnError},
+	}
+
+	for _, check := range checks {
+		if check.value == check.requires && !serverHello.HasCapability(check.capability) {
+			return nil, &UnsupportedOptionError{
+				Option:     check.option,
+				Value:      check.value,
+				Capability: check.capability,
+			}
+		}
+	}
+
+	return WrapMethod(&editConfigOperation{
+		Attr:             namespaceAttrs(opts.Namespaces),
+		Target:           datastoreParam(target),
+		DefaultOperation: opts.DefaultOperation,
+		TestOption:       opts.TestOption,
+		ErrorOption:      opts.ErrorOption,
+		Config:           editConfig{Payload: config},
+	}), nil
+}
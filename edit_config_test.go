@@ -0,0 +1,64 @@
+package netconf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestEditConfigWithOptions(t *testing.T) {
+
+	type System struct {
+		XMLName xml.Name `xml:"system"`
+	}
+
+	hello := &HelloMessage{Capabilities: []string{CapabilityCandidate, CapabilityValidate}}
+
+	method, err := EditConfigWithOptions(hello, DatastoreCandidate, &System{}, EditOptions{
+		DefaultOperation: DefaultOperationReplace,
+		TestOption:       TestOptionTestThenSet,
+		ErrorOption:      ErrorOptionStopOnError,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []byte(`<edit-config><target><candidate></candidate></target><default-operation>replace</default-operation>` +
+		`<test-option>test-then-set</test-option><error-option>stop-on-error</error-option><config><system></system></config></edit-config>`)
+	if got, err := MarshalMethod(method); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Contains(got, want) {
+		t.Errorf("unexpected operation\nwant:\t%q\ngot:\t%q", want, got)
+	}
+
+	_, err = EditConfigWithOptions(hello, DatastoreCandidate, &System{}, EditOptions{
+		ErrorOption: ErrorOptionRollbackOnError,
+	})
+	if optErr, ok := err.(*UnsupportedOptionError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", optErr, err)
+	} else if optErr.Capability != CapabilityRollbackOnError {
+		t.Errorf("unexpected capability\nwant:\t%q\ngot:\t%q", CapabilityRollbackOnError, optErr.Capability)
+	}
+
+	writable := &HelloMessage{Capabilities: []string{CapabilityWritableRunning}}
+	_, err = EditConfigWithOptions(writable, DatastoreRunning, &System{}, EditOptions{
+		TestOption: TestOptionTestOnly,
+	})
+	if optErr, ok := err.(*UnsupportedOptionError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", optErr, err)
+	} else if optErr.Capability != CapabilityValidate {
+		t.Errorf("unexpected capability\nwant:\t%q\ngot:\t%q", CapabilityValidate, optErr.Capability)
+	}
+
+	_, err = EditConfigWithOptions(&HelloMessage{}, DatastoreCandidate, &System{}, EditOptions{})
+	if dsErr, ok := err.(*UnsupportedDatastoreError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", dsErr, err)
+	}
+
+	_, err = EditConfigWithOptions(&HelloMessage{}, DatastoreRunning, &System{}, EditOptions{})
+	if dsErr, ok := err.(*UnsupportedDatastoreError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", dsErr, err)
+	} else if dsErr.Capability != CapabilityWritableRunning {
+		t.Errorf("unexpected capability\nwant:\t%q\ngot:\t%q", CapabilityWritableRunning, dsErr.Capability)
+	}
+}
@@ -3,8 +3,15 @@ package netconf
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"unicode/utf8"
 )
 
 const (
@@ -19,11 +26,32 @@ const (
 // It is useful for wrapping structs that don't
 // encode the outer rpc tags.
 //
-// Multiple methods can be encoded into one RPC.
+// RFC 6241 allows exactly one operation per RPC, so
+// Encoder only encodes multiple methods into one RPC
+// when AllowMulti is set, for vendors that accept it.
 type Method struct {
-	XMLName xml.Name
-	Attr    []xml.Attr `xml:",attr"`
-	Method  []interface{}
+	XMLName    xml.Name
+	Attr       []xml.Attr `xml:",attr"`
+	Method     []interface{}
+	AllowMulti bool `xml:"-"` // AllowMulti permits more than one method in the RPC.
+
+	// Namespaces maps prefixes to namespaces, which Encoder declares
+	// with xmlns:prefix attributes on the rpc element, so nested elements
+	// can be qualified by a prefix in their struct tags, e.g. "ns1:pbr",
+	// for servers that reject namespaces Go would otherwise declare on
+	// every element.
+	Namespaces map[string]string `xml:"-"`
+}
+
+// MultipleMethodsError is returned when encoding a Method containing
+// more than one method, without setting its AllowMulti field.
+type MultipleMethodsError struct {
+	Count int // Count is the number of methods in the RPC.
+}
+
+// Error is MultipleMethodsError's implementation of the error interface.
+func (e *MultipleMethodsError) Error() string {
+	return fmt.Sprintf("netconf: rpc contains %d methods, but AllowMulti is not set", e.Count)
 }
 
 // XMLNameTag returns an xml.Name for an RPC's outer tag.
@@ -49,26 +77,132 @@ func XMLAttr(messageID string) []xml.Attr {
 	}
 }
 
+// NamespaceAttr returns an xml.Attr declaring the given
+// namespace prefix (i.e. xmlns:prefix="namespace"). It can
+// be appended to a Method's Attr to declare additional
+// namespaces on the outer rpc tag.
+func NamespaceAttr(prefix, namespace string) xml.Attr {
+	return xml.Attr{
+		Name: xml.Name{
+			Local: "xmlns:" + prefix,
+		},
+		Value: namespace,
+	}
+}
+
+// namespaceAttrs returns an xmlns:prefix attribute for every
+// namespace, sorted by prefix.
+func namespaceAttrs(namespaces map[string]string) []xml.Attr {
+
+	prefixes := make([]string, 0, len(namespaces))
+	for prefix := range namespaces {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	attrs := make([]xml.Attr, len(prefixes))
+	for i, prefix := range prefixes {
+		attrs[i] = NamespaceAttr(prefix, namespaces[prefix])
+	}
+
+	return attrs
+}
+
 // WrapMethod wraps the given methods' with outer rpc
 // tags, and sets default values for namespace and
 // message id attributes. It returns a pointer to a
 // Method that can be directly marshaled into an RPC
 // by Encoder.
 func WrapMethod(method ...interface{}) *Method {
-	GlobalCounter.Add(1)
+	return WrapMethodNS(BaseNamespace, method...)
+}
+
+// WrapMethodNS is like WrapMethod, but the outer rpc
+// tag has the given namespace instead of BaseNamespace.
+func WrapMethodNS(namespace string, method ...interface{}) *Method {
+	return &Method{
+		XMLName: XMLNameTag(namespace),
+		Attr:    XMLAttr(strconv.FormatUint(GlobalCounter.Incr(), 10)),
+		Method:  method,
+	}
+}
+
+// WrapMethodID is like WrapMethod, but the message-id attribute
+// is set to the given messageID, and GlobalCounter is not incremented.
+func WrapMethodID(messageID string, method ...interface{}) *Method {
 	return &Method{
 		XMLName: XMLNameTag(BaseNamespace),
-		Attr:    XMLAttr(GlobalCounter.String()),
+		Attr:    XMLAttr(messageID),
 		Method:  method,
 	}
 }
 
+// InvalidMessageIDError is returned when a caller supplied message-id
+// is empty, or contains characters that are not allowed in XML.
+type InvalidMessageIDError struct {
+	MessageID string // MessageID is the rejected message-id.
+}
+
+// Error is InvalidMessageIDError's implementation of the error interface.
+func (e *InvalidMessageIDError) Error() string {
+	return fmt.Sprintf("netconf: invalid message-id %q", e.MessageID)
+}
+
+// validateMessageID returns an InvalidMessageIDError if the messageID
+// is empty, is not valid UTF-8, or contains characters outside of the
+// XML character range.
+func validateMessageID(messageID string) error {
+
+	if messageID == "" || !utf8.ValidString(messageID) {
+		return &InvalidMessageIDError{MessageID: messageID}
+	}
+
+	for _, r := range messageID {
+		if !isXMLChar(r) {
+			return &InvalidMessageIDError{MessageID: messageID}
+		}
+	}
+
+	return nil
+}
+
+// isXMLChar reports whether r is in the XML 1.0 Char production.
+func isXMLChar(r rune) bool {
+	return r == 0x09 || r == 0x0A || r == 0x0D ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
+
 // Encoder embeds an xml.Encoder, but overrides Encode
 // with a custom implementation designed specifically
 // to encode NETCONF RPC requests.
 type Encoder struct {
 	*xml.Encoder
 	bufWriter *bufio.Writer
+	closer    io.Closer // closed when a context is done to interrupt a pending write
+	poisoned  *error    // first write error, shared by every Encoder of a Session
+	sent      *uint64   // number of RPCs sent by a Session, if any
+	preEncode func(m *Method) (*Method, error)
+	indented  bool
+}
+
+// SessionPoisonedError is returned by every Encoder method after a
+// previous write failed, since the failed RPC may have been partially
+// written, leaving the framing of the stream in an indeterminate state.
+// The session must be closed.
+type SessionPoisonedError struct {
+	Err error // Err is the write error that poisoned the session.
+}
+
+// Error is SessionPoisonedError's implementation of the error interface.
+func (e *SessionPoisonedError) Error() string {
+	return fmt.Sprintf("netconf: session poisoned by a previous write error: %v", e.Err)
+}
+
+// Unwrap returns the write error that poisoned the session.
+func (e *SessionPoisonedError) Unwrap() error {
+	return e.Err
 }
 
 // NewEncoder buffers the given io.Writer, and wraps it
@@ -79,18 +213,68 @@ func NewEncoder(w io.Writer) *Encoder {
 
 	e.bufWriter = bufio.NewWriter(w)
 	e.Encoder = xml.NewEncoder(e.bufWriter)
+	e.closer, _ = w.(io.Closer)
+	e.poisoned = new(error)
 
 	return &e
 }
 
+// Indent sets the encoder to generate XML in which each element
+// begins on a new indented line that starts with prefix and is
+// followed by one or more copies of indent according to the nesting
+// depth. The message separator is written on its own line after
+// the indented RPC.
+func (e *Encoder) Indent(prefix, indent string) {
+	e.Encoder.Indent(prefix, indent)
+	e.indented = prefix != "" || indent != ""
+}
+
+// SetPreEncodeHook sets a function Encode calls with every RPC before
+// marshalling it, e.g. to log it, or to add an audit attribute. The hook
+// sees the final *Method, wrapped and carrying its message-id attribute,
+// and returns the *Method to encode instead, or nil to encode it as is.
+// It must not modify the given *Method, which may belong to the caller,
+// so it should return a modified copy. An error returned by the hook
+// aborts the encode before anything is written. A nil hook removes it.
+func (e *Encoder) SetPreEncodeHook(hook func(m *Method) (*Method, error)) {
+	e.preEncode = hook
+}
+
+// poisonedError returns a SessionPoisonedError if a previous
+// write failed, or nil otherwise.
+func (e *Encoder) poisonedError() error {
+	if e.poisoned != nil && *e.poisoned != nil {
+		return &SessionPoisonedError{Err: *e.poisoned}
+	}
+	return nil
+}
+
+// poison records the write error that left the stream
+// in an indeterminate state.
+func (e *Encoder) poison(err error) {
+	if e.poisoned != nil && *e.poisoned == nil {
+		*e.poisoned = err
+	}
+}
+
 // EncodeHello writes the given hello message to the
 // underlying writer, writes a message separator, and
-// flushes the buffer.
+// flushes the buffer. Like DecodeHello, it always uses
+// the "]]>]]>" separator, regardless of the capabilities
+// advertised.
 func (e *Encoder) EncodeHello(h *HelloMessage) error {
 
+	if err := e.poisonedError(); err != nil {
+		return err
+	}
+
 	if err := e.Encoder.Encode(h); err != nil {
+		e.poison(err)
 		return err
-	} else if err = e.WriteSep(); err != nil {
+	}
+
+	if err := e.writeSep(); err != nil {
+		e.poison(err)
 		return err
 	}
 
@@ -101,23 +285,260 @@ func (e *Encoder) EncodeHello(h *HelloMessage) error {
 // into the underlying buffer. Then it writes the NETCONF message
 // separator followed by a newline, and flushes the buffer.
 //
+// Values other than a *Method are wrapped with WrapMethod, which takes
+// the next message-id from GlobalCounter. RawEncode sends a complete
+// document without wrapping it.
+//
+// A MultipleMethodsError is returned if the argument is a *Method
+// with more than one method, and its AllowMulti field is not set.
+// If encoding or writing the RPC fails, it may have been partially
+// written, so every later call returns a SessionPoisonedError.
+//
 // Encoding XML as a stream of tokens is still possible using the
-// underlying xml.Encoder. However, WriteSep must should be called
-// after encoding an RPC.
+// underlying xml.Encoder's EncodeToken. Flush can be called to push a
+// partial RPC to the wire, and WriteSep must be called once the RPC is
+// complete.
 func (e *Encoder) Encode(v interface{}) error {
 
+	if err := e.poisonedError(); err != nil {
+		return err
+	}
+
 	method, ok := v.(*Method)
 	if !ok {
 		method = WrapMethod(v)
+	} else if len(method.Method) > 1 && !method.AllowMulti {
+		return &MultipleMethodsError{Count: len(method.Method)}
+	}
+
+	if len(method.Namespaces) != 0 {
+		m := *method
+		m.Attr = append(method.Attr[:len(method.Attr):len(method.Attr)], namespaceAttrs(method.Namespaces)...)
+		method = &m
+	}
+
+	if e.preEncode != nil {
+		hooked, err := e.preEncode(method)
+		if err != nil {
+			return err
+		} else if hooked != nil {
+			method = hooked
+		}
 	}
 
 	if err := e.Encoder.Encode(method); err != nil {
+		e.poison(err)
 		return err
-	} else if err = e.WriteSep(); err != nil {
+	}
+
+	return e.WriteSep()
+}
+
+// RawEncode marshals v as is, writes the message separator, and flushes
+// the buffer. Unlike Encode, v is never wrapped in an rpc element, and
+// no message-id is taken from GlobalCounter, so it is meant for complete
+// documents built by the caller, like an rpc element with its own
+// message-id, or a hello message. The pre-encode hook is not called,
+// since v is not a *Method.
+//
+// If encoding or writing fails, every later call returns a
+// SessionPoisonedError, just like Encode.
+func (e *Encoder) RawEncode(v interface{}) error {
+
+	if err := e.poisonedError(); err != nil {
 		return err
 	}
 
-	return nil
+	if err := e.Encoder.Encode(v); err != nil {
+		e.poison(err)
+		return err
+	}
+
+	return e.WriteSep()
+}
+
+// SeparatorInMessageError is returned by ReadFrom when the streamed
+// message contains the message separator, which would end the message
+// early on the wire. It can't appear in well-formed XML.
+type SeparatorInMessageError struct {
+	Offset int64 // Offset is the offset of the separator in the stream.
+}
+
+// Error is SeparatorInMessageError's implementation of the error interface.
+func (e *SeparatorInMessageError) Error() string {
+	return fmt.Sprintf("netconf: message separator at offset %d of the streamed message", e.Offset)
+}
+
+// ReadFrom implements the io.ReaderFrom interface. It copies a complete
+// message rendered beforehand, usually an rpc element with its message-id,
+// like an edit-config or copy-config of a multi-megabyte configuration
+// read from disk, from r to the wire, and then writes the message separator.
+// The message is streamed as it is read, so it is never held in memory
+// entirely, and it is sent as is, like RawEncode, without being parsed,
+// wrapped, or passed to the pre-encode hook. The count returned excludes
+// the separator.
+//
+// The only framing is the base:1.0 message separator, which is why a
+// SeparatorInMessageError is returned if the message contains one. If it
+// does, or if reading or writing fails, the message is left partially
+// written, so every later call returns a SessionPoisonedError.
+func (e *Encoder) ReadFrom(r io.Reader) (int64, error) {
+
+	if err := e.poisonedError(); err != nil {
+		return 0, err
+	}
+
+	// tokens encoded before must precede the message
+	if err := e.Encoder.Flush(); err != nil {
+		e.poison(err)
+		return 0, err
+	}
+
+	var (
+		written int64
+		scanner separatorScanner
+		buf     = make([]byte, writeToBufSize)
+	)
+
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			if i, found := scanner.scan(buf[:n]); found {
+				err := &SeparatorInMessageError{Offset: written + int64(i)}
+				e.poison(err)
+				return written, err
+			}
+
+			if _, err := e.bufWriter.Write(buf[:n]); err != nil {
+				e.poison(err)
+				return written, err
+			}
+			written += int64(n)
+		}
+
+		if readErr == io.EOF {
+			break
+		} else if readErr != nil {
+			e.poison(readErr)
+			return written, readErr
+		}
+	}
+
+	return written, e.WriteSep()
+}
+
+// separatorScanner finds the message separator in a stream scanned
+// chunk by chunk, including a separator split across chunks.
+type separatorScanner struct {
+	tail   []byte // end of the previous chunks, shorter than a separator
+	window []byte // tail followed by the beginning of the chunk
+}
+
+// scan reports whether a separator ends in the chunk, and the index in the
+// chunk where it starts, which is negative if it starts in a previous chunk.
+func (s *separatorScanner) scan(chunk []byte) (int, bool) {
+
+	const keep = len(MessageSeparator) - 1
+
+	head := chunk
+	if len(head) > keep {
+		head = head[:keep]
+	}
+
+	s.window = append(append(s.window[:0], s.tail...), head...)
+	if i := bytes.Index(s.window, messageSeparatorBytes); i != -1 {
+		return i - len(s.tail), true
+	} else if i = bytes.Index(chunk, messageSeparatorBytes); i != -1 {
+		return i, true
+	}
+
+	// the window holds the whole chunk when it is short
+	end := s.window
+	if len(chunk) > keep {
+		end = chunk
+	}
+	s.tail = append(s.tail[:0], end[len(end)-minInt(len(end), keep):]...)
+
+	return 0, false
+}
+
+// minInt returns the smaller of a and b.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// EncodeContext is like Encode, but it returns ctx.Err() if the
+// context is done before the RPC is written and flushed.
+//
+// A pending write can only be interrupted by closing the writer. If the
+// underlying io.Writer is also an io.Closer (e.g. the Session's stdin), it
+// is closed when the context is done, and the write is waited upon before
+// returning. Otherwise the pending write is abandoned. Either way, the
+// Encoder must not be used after EncodeContext returns a context error.
+func (e *Encoder) EncodeContext(ctx context.Context, v interface{}) error {
+	return e.doContext(ctx, func() error {
+		return e.Encode(v)
+	})
+}
+
+// doContext calls fn, which writes to the underlying writer, and
+// returns ctx.Err() if the context is done before fn returns. It
+// implements EncodeContext.
+func (e *Encoder) doContext(ctx context.Context, fn func() error) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// buffered so an abandoned write never blocks on send
+	ch := make(chan error, 1)
+	go func() {
+		ch <- fn()
+	}()
+
+	select {
+	case err := <-ch:
+		return err
+	case <-ctx.Done():
+		if e.closer != nil {
+			_ = e.closer.Close()
+			<-ch
+		}
+		return ctx.Err()
+	}
+}
+
+// EncodeWithID is like Encode, but the RPC's message-id attribute is
+// set to the given messageID instead of the next GlobalCounter value.
+// An InvalidMessageIDError is returned if the messageID is empty, or
+// contains characters that are not allowed in XML.
+//
+// If the argument's type is *Method, its message-id attribute is
+// replaced, without modifying the argument.
+func (e *Encoder) EncodeWithID(v interface{}, messageID string) error {
+
+	if err := validateMessageID(messageID); err != nil {
+		return err
+	}
+
+	method, ok := v.(*Method)
+	if !ok {
+		return e.Encode(WrapMethodID(messageID, v))
+	}
+
+	m := *method
+	m.Attr = make([]xml.Attr, 0, len(method.Attr)+1)
+	for _, attr := range method.Attr {
+		if attr.Name.Local != "message-id" {
+			m.Attr = append(m.Attr, attr)
+		}
+	}
+	m.Attr = append(XMLAttr(messageID), m.Attr...)
+
+	return e.Encode(&m)
 }
 
 // WriteSep writes a message separator with a trailing newline to
@@ -126,10 +547,67 @@ func (e *Encoder) Encode(v interface{}) error {
 // encoding XML tokens as a stream with EncodeToken, et al.
 //
 // Calls to WriteSep may block depending on the underlying net.Conn.
+// EncodeContext can be used to bound the time spent writing an RPC.
 //
 // Most uses will call Encode, which calls WriteSep internally.
 func (e *Encoder) WriteSep() error {
 
+	if err := e.poisonedError(); err != nil {
+		return err
+	}
+
+	if err := e.writeSep(); err != nil {
+		e.poison(err)
+		return err
+	}
+
+	if e.sent != nil {
+		atomic.AddUint64(e.sent, 1)
+	}
+
+	return nil
+}
+
+// Flush writes any buffered XML to the underlying io.Writer, without
+// writing a message separator. It overrides the embedded xml.Encoder's
+// Flush, which only flushes into the Encoder's own buffer. It allows an
+// RPC encoded as a stream of tokens to be sent as it is produced (e.g. a
+// huge edit-config), by calling EncodeToken and Flush repeatedly, and
+// then WriteSep once the RPC is complete.
+//
+// If the write fails, every later call returns a SessionPoisonedError.
+func (e *Encoder) Flush() error {
+
+	if err := e.poisonedError(); err != nil {
+		return err
+	}
+
+	if err := e.Encoder.Flush(); err != nil {
+		e.poison(err)
+		return err
+	} else if err = e.bufWriter.Flush(); err != nil {
+		e.poison(err)
+		return err
+	}
+
+	return nil
+}
+
+// writeSep implements WriteSep.
+func (e *Encoder) writeSep() error {
+
+	// flush the xml.Encoder's own buffer before writing
+	// directly to the underlying bufio.Writer
+	if err := e.Encoder.Flush(); err != nil {
+		return err
+	}
+
+	if e.indented {
+		if err := e.bufWriter.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+
 	if _, err := e.bufWriter.Write(messageSeparatorBytes); err != nil {
 		return err
 	} else if err = e.bufWriter.WriteByte('\n'); err != nil {
@@ -144,16 +622,79 @@ func (e *Encoder) WriteSep() error {
 // Marshal returns the NETCONF encoding of v, including message
 // separators and enclosing RPC tags.
 //
-// If the argument's type is *Method, MarshalRPC just calls xml.Marshal
+// If the argument's type is *Method, Marshal just calls xml.Marshal
 // internally to build the XML. Otherwise, it wraps its argument in the
-// default *Method before calling xml.Marshal.
+// default *Method before calling xml.Marshal, which increments
+// GlobalCounter as a side effect. Use MarshalWithID, or MarshalMethod,
+// to build an RPC's bytes without modifying any global state, e.g. for
+// logging or golden files.
 //
 // A NETCONF message separator and newline is always written to the end
 // of the message.
 func Marshal(v interface{}) ([]byte, error) {
+	return marshal(v, "")
+}
+
+// MarshalWithID is like Marshal, but the RPC's message-id attribute is
+// set to the given messageID, like EncodeWithID, so GlobalCounter is never
+// incremented, whatever the argument's type.
+func MarshalWithID(v interface{}, messageID string) ([]byte, error) {
+
+	if err := validateMessageID(messageID); err != nil {
+		return nil, err
+	}
+
+	return marshal(v, messageID)
+}
+
+// marshal implements Marshal and MarshalWithID. The next GlobalCounter
+// value is used as the message-id of v, if it is not a *Method, unless
+// a messageID is given.
+func marshal(v interface{}, messageID string) ([]byte, error) {
+
+	var b bytes.Buffer
+	enc := NewEncoder(&b)
+
+	var err error
+	if messageID == "" {
+		err = enc.Encode(v)
+	} else {
+		err = enc.EncodeWithID(v, messageID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// MarshalMethod is like Marshal, but it only accepts a *Method, so
+// GlobalCounter is never incremented. Combined with WrapMethodID,
+// it returns the bytes of an RPC with a fixed message-id:
+//
+//	b, err := MarshalMethod(WrapMethodID("101", &GetConfig{}))
+//
+// A MultipleMethodsError is returned if the Method has more than one
+// method, and its AllowMulti field is not set.
+func MarshalMethod(m *Method) ([]byte, error) {
+
+	if m == nil {
+		return nil, errors.New("netconf: MarshalMethod called with a nil *Method")
+	}
+
+	return marshal(m, "")
+}
+
+// MarshalIndent works like Marshal, but each XML element begins on
+// a new indented line that starts with prefix and is followed by one
+// or more copies of indent according to the nesting depth. The message
+// separator is written on its own line.
+func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
 
 	var b bytes.Buffer
-	if err := NewEncoder(&b).Encode(v); err != nil {
+	enc := NewEncoder(&b)
+	enc.Indent(prefix, indent)
+	if err := enc.Encode(v); err != nil {
 		return nil, err
 	}
 
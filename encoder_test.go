@@ -2,9 +2,14 @@ package netconf
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestEncoder_Encode(t *testing.T) {
@@ -31,6 +36,339 @@ func TestEncoder_Encode(t *testing.T) {
 	}
 }
 
+func TestEncoder_EncodeNS(t *testing.T) {
+
+	type ShowInterfacesRPC struct {
+		XMLName xml.Name  `xml:"get-interface-information"`
+		Terse   *struct{} `xml:"terse,omitempty"`
+	}
+
+	const junosNamespace = "http://xml.juniper.net/junos/15.1X49/junos"
+
+	method := WrapMethodNS(BaseNamespace, &ShowInterfacesRPC{Terse: &struct{}{}})
+	method.Attr = append(method.Attr, NamespaceAttr("junos", junosNamespace))
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(method); err != nil {
+		t.Fatal(err)
+	}
+
+	want := fmt.Sprintf(`<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="%s" xmlns:junos="%s"><get-interface-information><terse></terse></get-interface-information></rpc>]]>]]>
+`, method.Attr[0].Value, junosNamespace)
+
+	if got := buf.String(); want != got {
+		t.Errorf("unexpected bytes encoded\nwant:\t%q\ngot:\t%q", want, got)
+	}
+
+	method = WrapMethodNS(junosNamespace, &ShowInterfacesRPC{})
+
+	buf.Reset()
+	if err := NewEncoder(&buf).Encode(method); err != nil {
+		t.Fatal(err)
+	}
+
+	want = fmt.Sprintf(`<rpc xmlns="%s" message-id="%s"><get-interface-information></get-interface-information></rpc>]]>]]>
+`, junosNamespace, method.Attr[0].Value)
+
+	if got := buf.String(); want != got {
+		t.Errorf("unexpected bytes encoded\nwant:\t%q\ngot:\t%q", want, got)
+	}
+}
+
+func TestEncoder_EncodeWithID(t *testing.T) {
+
+	type ShowInterfacesRPC struct {
+		XMLName xml.Name `xml:"get-interface-information"`
+	}
+
+	want := `<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="req-42"><get-interface-information></get-interface-information></rpc>]]>]]>
+`
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).EncodeWithID(&ShowInterfacesRPC{}, "req-42"); err != nil {
+		t.Error(err)
+	} else if got := buf.String(); want != got {
+		t.Errorf("unexpected bytes encoded\nwant:\t%q\ngot:\t%q", want, got)
+	}
+
+	method := WrapMethod(&ShowInterfacesRPC{})
+	counterID := method.Attr[0].Value
+
+	buf.Reset()
+	if err := NewEncoder(&buf).EncodeWithID(method, "req-42"); err != nil {
+		t.Error(err)
+	} else if got := buf.String(); want != got {
+		t.Errorf("unexpected bytes encoded\nwant:\t%q\ngot:\t%q", want, got)
+	} else if method.Attr[0].Value != counterID {
+		t.Errorf("argument's message-id was modified\nwant:\t%q\ngot:\t%q",
+			counterID, method.Attr[0].Value)
+	}
+
+	for _, messageID := range []string{"", "bad\x00id", "bad\xffid"} {
+		buf.Reset()
+		if err := NewEncoder(&buf).EncodeWithID(&ShowInterfacesRPC{}, messageID); err == nil {
+			t.Errorf("expected an error encoding message-id %q", messageID)
+		} else if _, ok := err.(*InvalidMessageIDError); !ok {
+			t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", &InvalidMessageIDError{}, err)
+		} else if buf.Len() != 0 {
+			t.Errorf("unexpected bytes encoded with invalid message-id: %q", buf.Bytes())
+		}
+	}
+}
+
+func TestEncoder_EncodeMultipleMethods(t *testing.T) {
+
+	type Lock struct {
+		XMLName xml.Name `xml:"lock"`
+	}
+
+	type Unlock struct {
+		XMLName xml.Name `xml:"unlock"`
+	}
+
+	var buf bytes.Buffer
+	method := WrapMethodID("1", &Lock{}, &Unlock{})
+
+	if err := NewEncoder(&buf).Encode(method); err == nil {
+		t.Error("expected an error encoding multiple methods")
+	} else if multiErr, ok := err.(*MultipleMethodsError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", multiErr, err)
+	} else if multiErr.Count != 2 {
+		t.Errorf("unexpected method count:\nwant:\t%d\ngot:\t%d", 2, multiErr.Count)
+	} else if buf.Len() != 0 {
+		t.Errorf("unexpected bytes encoded: %q", buf.Bytes())
+	}
+
+	want := `<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1"><lock></lock><unlock></unlock></rpc>]]>]]>
+`
+
+	method.AllowMulti = true
+	if err := NewEncoder(&buf).Encode(method); err != nil {
+		t.Error(err)
+	} else if got := buf.String(); want != got {
+		t.Errorf("unexpected bytes encoded\nwant:\t%q\ngot:\t%q", want, got)
+	}
+}
+
+// blockingWriteCloser blocks every Write until it is closed.
+type blockingWriteCloser struct {
+	closed chan struct{}
+}
+
+func (bwc *blockingWriteCloser) Write(p []byte) (int, error) {
+	<-bwc.closed
+	return 0, io.ErrClosedPipe
+}
+
+func (bwc *blockingWriteCloser) Close() error {
+	close(bwc.closed)
+	return nil
+}
+
+func TestEncoder_EncodeContext(t *testing.T) {
+
+	type ShowInterfacesRPC struct {
+		XMLName xml.Name `xml:"get-interface-information"`
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	bwc := &blockingWriteCloser{closed: make(chan struct{})}
+	begin := time.Now()
+
+	if err := NewEncoder(bwc).EncodeContext(ctx, &ShowInterfacesRPC{}); err != context.DeadlineExceeded {
+		t.Errorf("unexpected error:\nwant:\t%v\ngot:\t%v", context.DeadlineExceeded, err)
+	} else if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("encode was not interrupted promptly: %s", elapsed)
+	}
+
+	want := `<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1"><get-interface-information></get-interface-information></rpc>]]>]]>
+`
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).EncodeContext(context.Background(), WrapMethodID("1", &ShowInterfacesRPC{})); err != nil {
+		t.Error(err)
+	} else if got := buf.String(); want != got {
+		t.Errorf("unexpected bytes encoded\nwant:\t%q\ngot:\t%q", want, got)
+	}
+}
+
+// failingWriter returns its error on every Write.
+type failingWriter struct {
+	err error
+}
+
+func (fw failingWriter) Write(p []byte) (int, error) {
+	return 0, fw.err
+}
+
+func TestEncoder_Encode_Poisoned(t *testing.T) {
+
+	type ShowInterfacesRPC struct {
+		XMLName xml.Name `xml:"get-interface-information"`
+	}
+
+	writeErr := errors.New("broken pipe")
+	enc := NewEncoder(failingWriter{err: writeErr})
+
+	if err := enc.Encode(&ShowInterfacesRPC{}); err != writeErr {
+		t.Fatalf("unexpected error:\nwant:\t%v\ngot:\t%v", writeErr, err)
+	}
+
+	for _, err := range []error{enc.Encode(&ShowInterfacesRPC{}), enc.WriteSep()} {
+		if poisonedErr, ok := err.(*SessionPoisonedError); !ok {
+			t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", poisonedErr, err)
+		} else if !errors.Is(err, writeErr) {
+			t.Errorf("SessionPoisonedError does not unwrap to %v: %v", writeErr, err)
+		}
+	}
+}
+
+func TestEncoder_SetPreEncodeHook(t *testing.T) {
+
+	type ShowInterfacesRPC struct {
+		XMLName xml.Name `xml:"get-interface-information"`
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+
+	var gotID string
+	enc.SetPreEncodeHook(func(m *Method) (*Method, error) {
+		for _, attr := range m.Attr {
+			if attr.Name.Local == "message-id" {
+				gotID = attr.Value
+			}
+		}
+		audited := *m
+		audited.Attr = append(m.Attr[:len(m.Attr):len(m.Attr)], xml.Attr{Name: xml.Name{Local: "audit"}, Value: "ticket-42"})
+		return &audited, nil
+	})
+
+	if err := enc.Encode(WrapMethodID("101", &ShowInterfacesRPC{})); err != nil {
+		t.Fatal(err)
+	} else if gotID != "101" {
+		t.Errorf("hook did not see the message-id\nwant:\t%q\ngot:\t%q", "101", gotID)
+	}
+
+	want := []byte(`<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="101" audit="ticket-42"><get-interface-information></get-interface-information></rpc>]]>]]>
+`)
+	if !bytes.Equal(want, buf.Bytes()) {
+		t.Errorf("unexpected bytes encoded\nwant:\t%q\ngot:\t%q", want, buf.Bytes())
+	}
+
+	hookErr := errors.New("unsigned rpc")
+	enc.SetPreEncodeHook(func(m *Method) (*Method, error) {
+		return nil, hookErr
+	})
+
+	buf.Reset()
+	if err := enc.Encode(&ShowInterfacesRPC{}); err != hookErr {
+		t.Errorf("unexpected error:\nwant:\t%v\ngot:\t%v", hookErr, err)
+	} else if buf.Len() != 0 {
+		t.Errorf("an aborted RPC was written: %q", buf.Bytes())
+	}
+
+	// the aborted RPC was never written, so the encoder is not poisoned
+	enc.SetPreEncodeHook(nil)
+	if err := enc.Encode(&ShowInterfacesRPC{}); err != nil {
+		t.Errorf("unexpected error after an aborted RPC: %v", err)
+	}
+}
+
+func TestEncoder_Flush(t *testing.T) {
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+
+	rpc := xml.StartElement{Name: XMLNameTag(BaseNamespace), Attr: XMLAttr("1")}
+	if err := enc.EncodeToken(rpc); err != nil {
+		t.Fatal(err)
+	} else if err = enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the partial RPC reaches the writer before the RPC is complete
+	want := `<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1">`
+	if got := buf.String(); want != got {
+		t.Errorf("unexpected bytes flushed\nwant:\t%q\ngot:\t%q", want, got)
+	}
+
+	if err := enc.EncodeToken(rpc.End()); err != nil {
+		t.Fatal(err)
+	} else if err = enc.WriteSep(); err != nil {
+		t.Fatal(err)
+	}
+
+	want += "</rpc>" + MessageSeparator + "\n"
+	if got := buf.String(); want != got {
+		t.Errorf("unexpected bytes written\nwant:\t%q\ngot:\t%q", want, got)
+	}
+}
+
+func TestEncoder_ReadFrom(t *testing.T) {
+
+	rpc := `<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="push-1"><edit-config><target><candidate/></target><config><system><host-name><![CDATA[a]]>]]</host-name></system></config></edit-config></rpc>`
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+
+	// split so a read ends with what looks like the start of a separator
+	i := strings.Index(rpc, "]]>]]") + len("]]>]]")
+	n, err := enc.ReadFrom(&chunkReader{chunks: []string{rpc[:i-3], rpc[i-3 : i], rpc[i:]}})
+	if err != nil {
+		t.Fatal(err)
+	} else if n != int64(len(rpc)) {
+		t.Errorf("unexpected byte count\nwant:\t%d\ngot:\t%d", len(rpc), n)
+	}
+
+	want := rpc + MessageSeparator + "\n"
+	if got := buf.String(); want != got {
+		t.Errorf("unexpected bytes encoded\nwant:\t%q\ngot:\t%q", want, got)
+	}
+
+	// a separator split across reads would end the message early
+	buf.Reset()
+	enc = NewEncoder(&buf)
+	_, err = enc.ReadFrom(&chunkReader{chunks: []string{"<rpc>]]>]", "]>", "</rpc>"}})
+
+	var sepErr *SeparatorInMessageError
+	if !errors.As(err, &sepErr) {
+		t.Fatalf("unexpected error\nwant:\t%T\ngot:\t%v", sepErr, err)
+	} else if sepErr.Offset != 5 {
+		t.Errorf("unexpected separator offset\nwant:\t%d\ngot:\t%d", 5, sepErr.Offset)
+	}
+
+	var poisonedErr *SessionPoisonedError
+	if err = enc.Encode(Get(Filter{})); !errors.As(err, &poisonedErr) {
+		t.Errorf("unexpected error after a failed ReadFrom\nwant:\t%T\ngot:\t%v", poisonedErr, err)
+	}
+}
+
+func TestEncoder_RawEncode(t *testing.T) {
+
+	type RPC struct {
+		XMLName   xml.Name `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 rpc"`
+		MessageID string   `xml:"message-id,attr"`
+		Get       struct{} `xml:"get"`
+	}
+
+	var buf bytes.Buffer
+	before := GlobalCounter.Value()
+
+	if err := NewEncoder(&buf).RawEncode(&RPC{MessageID: "custom-7"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="custom-7"><get></get></rpc>]]>]]>` + "\n"
+	if got := buf.String(); want != got {
+		t.Errorf("unexpected bytes encoded\nwant:\t%q\ngot:\t%q", want, got)
+	} else if after := GlobalCounter.Value(); before != after {
+		t.Errorf("RawEncode changed the counter\nwant:\t%d\ngot:\t%d", before, after)
+	}
+}
+
 func BenchmarkEncoder_Encode(b *testing.B) {
 
 	type ShowInterfacesRPC struct {
@@ -56,6 +394,21 @@ func BenchmarkEncoder_Encode(b *testing.B) {
 	}
 }
 
+func BenchmarkWrapMethod(b *testing.B) {
+
+	type ShowInterfacesRPC struct {
+		XMLName xml.Name `xml:"get-interface-information"`
+	}
+
+	showIfaceRPC := ShowInterfacesRPC{}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		WrapMethod(&showIfaceRPC)
+	}
+}
+
 func Test_Marshal(t *testing.T) {
 
 	type ShowInterfacesRPC struct {
@@ -81,3 +434,87 @@ func Test_Marshal(t *testing.T) {
 		t.Log("successfully marshalled get-interface-information rpc")
 	}
 }
+
+func Test_MarshalIndent(t *testing.T) {
+
+	type ShowInterfacesRPC struct {
+		XMLName xml.Name  `xml:"get-interface-information"`
+		Detail  *struct{} `xml:"detail,omitempty"`
+	}
+
+	method := WrapMethodID("101", &ShowInterfacesRPC{Detail: &struct{}{}})
+
+	b, err := MarshalIndent(method, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="101">
+  <get-interface-information>
+    <detail></detail>
+  </get-interface-information>
+</rpc>
+]]>]]>
+`
+
+	if got := string(b); want != got {
+		t.Errorf("unexpected bytes encoded\nwant:\t%q\ngot:\t%q", want, got)
+	}
+}
+
+func Test_MarshalWithID(t *testing.T) {
+
+	type ShowInterfacesRPC struct {
+		XMLName xml.Name `xml:"get-interface-information"`
+	}
+
+	before := GlobalCounter.Value()
+
+	b, err := MarshalWithID(&ShowInterfacesRPC{}, "101")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="101"><get-interface-information></get-interface-information></rpc>]]>]]>
+`
+
+	if got := string(b); want != got {
+		t.Errorf("unexpected bytes encoded\nwant:\t%q\ngot:\t%q", want, got)
+	} else if after := GlobalCounter.Value(); before != after {
+		t.Errorf("GlobalCounter was modified\nwant:\t%d\ngot:\t%d", before, after)
+	}
+
+	var idErr *InvalidMessageIDError
+	if _, err := MarshalWithID(&ShowInterfacesRPC{}, ""); !errors.As(err, &idErr) {
+		t.Errorf("unexpected error for an empty message-id:\nwant:\t%T\ngot:\t%v", idErr, err)
+	} else if after := GlobalCounter.Value(); before != after {
+		t.Errorf("GlobalCounter was modified\nwant:\t%d\ngot:\t%d", before, after)
+	}
+}
+
+func Test_MarshalMethod(t *testing.T) {
+
+	type ShowInterfacesRPC struct {
+		XMLName xml.Name `xml:"get-interface-information"`
+	}
+
+	before := GlobalCounter.Value()
+
+	b, err := MarshalMethod(WrapMethodID("101", &ShowInterfacesRPC{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="101"><get-interface-information></get-interface-information></rpc>]]>]]>
+`
+
+	if got := string(b); want != got {
+		t.Errorf("unexpected bytes encoded\nwant:\t%q\ngot:\t%q", want, got)
+	} else if after := GlobalCounter.Value(); before != after {
+		t.Errorf("GlobalCounter was modified\nwant:\t%d\ngot:\t%d", before, after)
+	}
+
+	if _, err := MarshalMethod(nil); err == nil {
+		t.Error("expected an error marshalling a nil *Method")
+	}
+}
@@ -2,14 +2,14 @@ package netconf
 
 import (
 	"bytes"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"sort"
+	"strings"
 )
 
-// TODO: Add a flag to return errors for warnings when constructing a Decoder.
-// TODO: Remove *Unknown constants, and return errors with zero values.
 // TODO: Use uint instead of uint64
-// TODO: Make a better Error() implementation using more of the ReplyError data.
 
 // UnmarshalTextError is returned when UnmarshalText fails to parse
 // the text it's given.
@@ -29,7 +29,7 @@ type ErrorSeverity uint
 const (
 	ErrorSeverityZero    ErrorSeverity = iota // ErrorSeverityZero represents an uninitialized ErrorSeverity value.
 	ErrorSeverityError                        // ErrorSeverityError indicates the severity is on the error level.
-	ErrorSeverityUnknown                      // ErrorSeverityUnknown means the ErrorSeverity could not be identified, and may indicate an internal error.
+	ErrorSeverityUnknown                      // Deprecated: ErrorSeverityUnknown is only set when the text is literally "unknown". Parse failures leave the zero value.
 	ErrorSeverityWarning                      // ErrorSeverityWarning is not yet utilized, according to RFC 6241.
 )
 
@@ -56,20 +56,27 @@ func (es ErrorSeverity) String() string {
 // UnmarshalText sets the receiver to the constant represented
 // by the text argument given. If the text argument does not
 // represent a known ErrorSeverity, it is set to the
-// ErrorSeverityUnknown constant, and an UnmarshalTextError
+// ErrorSeverityZero constant, and an UnmarshalTextError
 // is returned.
 func (es *ErrorSeverity) UnmarshalText(text []byte) error {
 
-	sText := string(bytes.ToLower(bytes.TrimSpace(text)))
-	if i := sort.SearchStrings(errorSeverityStringArray[:], sText); i != len(errorSeverityStringArray) && errorSeverityStringArray[i] == sText {
+	if i, ok := searchStringArray(errorSeverityStringArray[:], text); ok {
 		*es = ErrorSeverity(i)
 		return nil
 	}
 
-	*es = ErrorSeverityUnknown
+	*es = ErrorSeverityZero
 	return &UnmarshalTextError{Type: "ErrorSeverity", Value: string(text)}
 }
 
+// searchStringArray searches the sorted string array for the
+// trimmed, lower case text, and returns its index if found.
+func searchStringArray(a []string, text []byte) (int, bool) {
+	sText := string(bytes.ToLower(bytes.TrimSpace(text)))
+	i := sort.SearchStrings(a, sText)
+	return i, i != len(a) && a[i] == sText
+}
+
 // ErrorInfo contains protocol or data model specific error content.
 type ErrorInfo struct {
 	BadAttribute string   `xml:"bad-attribute"` // BadAttribute has the name(s) of the bad, missing, or unexpected attribute(s).
@@ -78,6 +85,7 @@ type ErrorInfo struct {
 	OkElement    []string `xml:"ok-element"`    // OkElement is the parent element for which all children have completed the requested operation.
 	ErrElement   []string `xml:"err-element"`   // ErrElement is the parent element for which all children have failed to complete the requested operation.
 	NOPElement   []string `xml:"noop-element"`  // NOPElement is the parent element that identifies all children for which the requested operation was not attempted.
+	SessionID    uint     `xml:"session-id"`    // SessionID identifies the session holding the requested lock when the tag is lock-denied, or zero if the lock is held by a non-NETCONF entity.
 }
 
 // ErrorType defines the conceptual layer that the error occurred in.
@@ -95,6 +103,8 @@ const (
 	// ErrorTypeTransport indicates the error occurred on the Secure Transport layer, which provides a communication path between the client and server.
 	ErrorTypeTransport
 	// ErrorTypeUnknown indicates an unexpected condition.
+	//
+	// Deprecated: ErrorTypeUnknown is only set when the text is literally "unknown". Parse failures leave the zero value.
 	ErrorTypeUnknown
 )
 
@@ -123,17 +133,16 @@ func (es ErrorType) String() string {
 // UnmarshalText sets the ErrorType receiver to the constant
 // represented by the text argument given. If the text argument
 // does not represent a known ErrorType, it is set
-// to the ErrorTypeUnknown constant, and an UnmarshalTextError
+// to the ErrorTypeZero constant, and an UnmarshalTextError
 // is returned.
 func (es *ErrorType) UnmarshalText(text []byte) error {
 
-	sText := string(bytes.ToLower(bytes.TrimSpace(text)))
-	if i := sort.SearchStrings(errorTypeStringArray[:], sText); i != len(errorTypeStringArray) && errorTypeStringArray[i] == sText {
+	if i, ok := searchStringArray(errorTypeStringArray[:], text); ok {
 		*es = ErrorType(i)
 		return nil
 	}
 
-	*es = ErrorTypeUnknown
+	*es = ErrorTypeZero
 	return &UnmarshalTextError{Type: "ErrorType", Value: string(text)}
 }
 
@@ -159,7 +168,7 @@ const (
 	ErrorTagResourceDenied                   // ErrorTagResourceDenied indicates insufficient resources.
 	ErrorTagRollbackFailed                   // ErrorTagRollbackFailed indicates the rollback was not completed.
 	ErrorTagTooBig                           // ErrorTagTooBig indicates the request or response is too large to handle.
-	ErrorTagUnknown                          // ErrorTagUnknown probably indicates an internal error, because the error type could not be identified.
+	ErrorTagUnknown                          // Deprecated: ErrorTagUnknown is only set when the text is literally "unknown". Parse failures leave the zero value.
 	ErrorTagUnknownAttribute                 // ErrorTagUnknownAttribute indicates an unexpected attribute is present. ErrorInfo's BadAttribute and BadElement field will contain more detail.
 	ErrorTagUnknownElement                   // ErrorTagUnknownElement indicates an unexpected element. ErrorInfo's BadElement field will contain its name.
 	ErrorTagUnknownNamespace                 // ErrorTagUnknownNamespace indicates an unexpected namespace is present. ErrorInfo's BadElement and BadNamespace fields will contain more detail.
@@ -202,7 +211,9 @@ func (et ErrorTag) String() string {
 	return errorTagStringArray[ErrorTagUnknown]
 }
 
-// Severity returns the severity of this ErrorTag.
+// Severity returns the severity of this ErrorTag. The
+// ErrorSeverityZero constant is returned for tags that
+// are zero, or not defined by RFC 6241.
 func (et ErrorTag) Severity() ErrorSeverity {
 	switch et {
 	case ErrorTagZero:
@@ -229,42 +240,258 @@ func (et ErrorTag) Severity() ErrorSeverity {
 		ErrorTagMalformedMessage:
 		return ErrorSeverityError
 	default:
-		return ErrorSeverityUnknown
+		return ErrorSeverityZero
 	}
 }
 
 // UnmarshalText sets the ErrorTag receiver to the constant
 // represented by the text argument given. If the text argument
 // does not represent a known ErrorTag, the ErrorTag is set
-// to the ErrorTagUnknown constant, and an UnmarshalTextError
+// to the ErrorTagZero constant, and an UnmarshalTextError
 // is returned.
 func (et *ErrorTag) UnmarshalText(text []byte) error {
 
-	sText := string(bytes.ToLower(bytes.TrimSpace(text)))
-	if i := sort.SearchStrings(errorTagStringArray[:], sText); i != len(errorTagStringArray) &&
-		errorTagStringArray[i] == sText {
+	if i, ok := searchStringArray(errorTagStringArray[:], text); ok {
 		*et = ErrorTag(i)
 		return nil
 	}
 
-	*et = ErrorTagUnknown
+	*et = ErrorTagZero
 	return &UnmarshalTextError{Type: "ErrorTag", Value: string(text)}
 }
 
+// Sentinel errors for each ErrorTag, allowing ReplyErrors to be matched
+// with errors.Is. Their messages are the tag strings, prefixed by "netconf: ".
+var (
+	ErrAccessDenied     = errors.New("netconf: " + ErrorTagAccessDenied.String())
+	ErrBadAttribute     = errors.New("netconf: " + ErrorTagBadAttribute.String())
+	ErrBadElement       = errors.New("netconf: " + ErrorTagBadElement.String())
+	ErrDataExists       = errors.New("netconf: " + ErrorTagDataExists.String())
+	ErrDataMissing      = errors.New("netconf: " + ErrorTagDataMissing.String())
+	ErrInUse            = errors.New("netconf: " + ErrorTagInUse.String())
+	ErrInvalidValue     = errors.New("netconf: " + ErrorTagInvalidValue.String())
+	ErrLockDenied       = errors.New("netconf: " + ErrorTagLockDenied.String())
+	ErrMalformedMessage = errors.New("netconf: " + ErrorTagMalformedMessage.String())
+	ErrMissingAttribute = errors.New("netconf: " + ErrorTagMissingAttribute.String())
+	ErrMissingElement   = errors.New("netconf: " + ErrorTagMissingElement.String())
+	ErrOpFailed         = errors.New("netconf: " + ErrorTagOpFailed.String())
+	ErrOpNotSupported   = errors.New("netconf: " + ErrorTagOpNotSupported.String())
+	ErrOpPartial        = errors.New("netconf: " + ErrorTagOpPartial.String())
+	ErrResourceDenied   = errors.New("netconf: " + ErrorTagResourceDenied.String())
+	ErrRollbackFailed   = errors.New("netconf: " + ErrorTagRollbackFailed.String())
+	ErrTooBig           = errors.New("netconf: " + ErrorTagTooBig.String())
+	ErrUnknownAttribute = errors.New("netconf: " + ErrorTagUnknownAttribute.String())
+	ErrUnknownElement   = errors.New("netconf: " + ErrorTagUnknownElement.String())
+	ErrUnknownNamespace = errors.New("netconf: " + ErrorTagUnknownNamespace.String())
+)
+
+// errorTagSentinelArray maps each ErrorTag to its sentinel error.
+// ErrorTagZero and ErrorTagUnknown have no sentinel.
+var errorTagSentinelArray = [...]error{
+	ErrorTagZero:             nil,
+	ErrorTagAccessDenied:     ErrAccessDenied,
+	ErrorTagBadAttribute:     ErrBadAttribute,
+	ErrorTagBadElement:       ErrBadElement,
+	ErrorTagDataExists:       ErrDataExists,
+	ErrorTagDataMissing:      ErrDataMissing,
+	ErrorTagInUse:            ErrInUse,
+	ErrorTagInvalidValue:     ErrInvalidValue,
+	ErrorTagLockDenied:       ErrLockDenied,
+	ErrorTagMalformedMessage: ErrMalformedMessage,
+	ErrorTagMissingAttribute: ErrMissingAttribute,
+	ErrorTagMissingElement:   ErrMissingElement,
+	ErrorTagOpFailed:         ErrOpFailed,
+	ErrorTagOpNotSupported:   ErrOpNotSupported,
+	ErrorTagOpPartial:        ErrOpPartial,
+	ErrorTagResourceDenied:   ErrResourceDenied,
+	ErrorTagRollbackFailed:   ErrRollbackFailed,
+	ErrorTagTooBig:           ErrTooBig,
+	ErrorTagUnknown:          nil,
+	ErrorTagUnknownAttribute: ErrUnknownAttribute,
+	ErrorTagUnknownElement:   ErrUnknownElement,
+	ErrorTagUnknownNamespace: ErrUnknownNamespace,
+}
+
 // ReplyError encapsulates a NETCONF RPC error, and implements the error interface.
 type ReplyError struct {
 	Type     ErrorType     `xml:"error-type"`     // Type is the conceptual layer that the error occurred.
 	Tag      ErrorTag      `xml:"error-tag"`      // Tag identifies the error condition.
 	Severity ErrorSeverity `xml:"error-severity"` // Severity is the error severity: either error or warning.
+	AppTag   string        `xml:"error-app-tag"`  // AppTag identifies the data-model-specific or implementation-specific error condition.
 	Info     ErrorInfo     `xml:"error-info"`     // Info contains protocol or data-model-specific error content.
 	Path     string        `xml:"error-path"`     // Path is the absolute XPath expression identifying the element path to the node.
 	Message  string        `xml:"error-message"`  // Message is a human friendly description of the error.
+
+	// PathNamespaces maps the namespace prefixes declared on the
+	// error-path element to their URIs, e.g. "ns1" to
+	// "http://cisco.com/ns/yang/Cisco-IOS-XR-pbr-cfg".
+	PathNamespaces map[string]string `xml:"-"`
+}
+
+// errorPath captures the error-path element's text along with the
+// namespace prefixes declared on it, which are otherwise discarded.
+type errorPath struct {
+	Path       string
+	Namespaces map[string]string
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (p *errorPath) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" {
+			if p.Namespaces == nil {
+				p.Namespaces = make(map[string]string)
+			}
+			p.Namespaces[attr.Name.Local] = attr.Value
+		}
+	}
+
+	return d.DecodeElement(&p.Path, &start)
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface. It decodes
+// the rpc-error element as usual, but also keeps the namespace
+// prefixes declared on the error-path element in PathNamespaces.
+func (e *ReplyError) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+
+	type replyError ReplyError
+	v := struct {
+		*replyError
+		Path errorPath `xml:"error-path"`
+	}{replyError: (*replyError)(e)}
+
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+
+	e.Path = v.Path.Path
+	e.PathNamespaces = v.Path.Namespaces
+	return nil
+}
+
+// ResolvedPath returns Path with every namespace prefix declared in
+// PathNamespaces expanded to the {uri}local form, e.g.:
+//
+//	{http://cisco.com/ns/yang/Cisco-IOS-XR-ifmgr-cfg}interface-configurations/{http://cisco.com/ns/yang/Cisco-IOS-XR-pbr-cfg}pbr
+//
+// Steps with an undeclared prefix, or no prefix at all, are left as is.
+func (e *ReplyError) ResolvedPath() string {
+
+	path := strings.TrimSpace(e.Path)
+	if len(e.PathNamespaces) == 0 {
+		return path
+	}
+
+	steps := strings.Split(path, "/")
+	for i, step := range steps {
+		if j := strings.IndexByte(step, ':'); j != -1 {
+			if uri, ok := e.PathNamespaces[step[:j]]; ok {
+				steps[i] = "{" + uri + "}" + step[j+1:]
+			}
+		}
+	}
+
+	return strings.Join(steps, "/")
 }
 
-// Error is the implementation of the error interface.
+// Error is the implementation of the error interface. It formats
+// the error as a single line, e.g.:
+//
+//	netconf: protocol/unknown-element at /ns2:config/ns1:pbr bad-element pbr [app-tag]: message
+//
+// Empty fields are omitted, and the severity is only included when
+// it is not error.
 func (e *ReplyError) Error() string {
-	if e.Message != "" {
-		return e.Message
+
+	var b strings.Builder
+
+	b.WriteString("netconf: ")
+	if e.Type != ErrorTypeZero {
+		b.WriteString(e.Type.String())
+		b.WriteByte('/')
+	}
+	b.WriteString(e.Tag.String())
+
+	if e.Severity != ErrorSeverityZero && e.Severity != ErrorSeverityError {
+		fmt.Fprintf(&b, " (%s)", e.Severity)
+	}
+
+	if path := strings.TrimSpace(e.Path); path != "" {
+		fmt.Fprintf(&b, " at %s", path)
+	}
+
+	if e.Info.BadElement != "" {
+		fmt.Fprintf(&b, " bad-element %s", e.Info.BadElement)
+	}
+
+	if e.AppTag != "" {
+		fmt.Fprintf(&b, " [%s]", e.AppTag)
+	}
+
+	if msg := strings.TrimSpace(e.Message); msg != "" {
+		fmt.Fprintf(&b, ": %s", msg)
+	}
+
+	return b.String()
+}
+
+// Is reports whether the target is the sentinel error for this
+// ReplyError's Tag, e.g. errors.Is(err, ErrAccessDenied).
+func (e *ReplyError) Is(target error) bool {
+	return target != nil && int(e.Tag) < len(errorTagSentinelArray) &&
+		errorTagSentinelArray[e.Tag] == target
+}
+
+// LockDeniedError is returned instead of a ReplyError when a reply contains
+// a single error tagged lock-denied, so the session holding the lock is
+// at hand, e.g. to kill it, or to report who to contact.
+type LockDeniedError struct {
+	SessionID uint        // SessionID is the session holding the lock, or zero if it is held by a non-NETCONF entity.
+	Err       *ReplyError // Err is the lock-denied error in the reply.
+}
+
+// Error is LockDeniedError's implementation of the error interface.
+func (e *LockDeniedError) Error() string {
+	if e.SessionID == 0 {
+		return fmt.Sprintf("%v (lock held by a non-NETCONF entity)", e.Err)
+	}
+	return fmt.Sprintf("%v (lock held by session %d)", e.Err, e.SessionID)
+}
+
+// Unwrap returns the ReplyError, so errors.Is(err, ErrLockDenied) holds.
+func (e *LockDeniedError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError is returned when a reply contains more than one ReplyError.
+type MultiError struct {
+	errs []*ReplyError
+}
+
+// Errors returns every ReplyError in the order they were received.
+func (me *MultiError) Errors() []*ReplyError {
+	return me.errs
+}
+
+// Error is the implementation of the error interface. It joins the
+// messages of every ReplyError.
+func (me *MultiError) Error() string {
+	var b strings.Builder
+	for i, err := range me.errs {
+		if i != 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns every ReplyError for use with errors.Is and errors.As.
+func (me *MultiError) Unwrap() []error {
+	errs := make([]error, len(me.errs))
+	for i, err := range me.errs {
+		errs[i] = err
 	}
-	return fmt.Sprintf("%s %s %s", e.Severity, e.Tag, e.Info.BadElement)
+	return errs
 }
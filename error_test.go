@@ -1,8 +1,10 @@
 package netconf
 
 import (
+	"errors"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 )
 
@@ -58,7 +60,7 @@ func TestError_Unmarshal(t *testing.T) {
 `
 
 	var reply1 Reply
-	if err := Unmarshal([]byte(err1), &reply1); err.Error() != "error unknown-element pbr" {
+	if err := Unmarshal([]byte(err1), &reply1); err.Error() != "netconf: protocol/unknown-element at ns2:interface-configurations/ns2:interface-configuration/ns1:pbr bad-element pbr" {
 		t.Errorf("unexpected error unmarshalling reply: %v", err)
 	} else if reply1.Error[0].Type != ErrorTypeProtocol {
 		t.Errorf("unexpected error type:\nwant:\t%q\ngot:\t%q",
@@ -75,6 +77,115 @@ func TestError_Unmarshal(t *testing.T) {
 	} else if want := "pbr"; want != reply1.Error[0].Info.BadElement {
 		t.Errorf("unexpected error path:\nwant:\t%q\ngot:\t%q",
 			want, reply1.Error[0].Info.BadElement)
+	} else if want := "101"; want != reply1.MessageID() {
+		t.Errorf("unexpected message-id:\nwant:\t%q\ngot:\t%q",
+			want, reply1.MessageID())
+	} else if want := BaseNamespace; want != reply1.Namespace() {
+		t.Errorf("unexpected namespace:\nwant:\t%q\ngot:\t%q",
+			want, reply1.Namespace())
+	}
+}
+
+func TestError_ResolvedPath(t *testing.T) {
+	const err1 = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="101">
+<rpc-error>
+<error-type>protocol</error-type>
+<error-tag>unknown-element</error-tag>
+<error-severity>error</error-severity>
+<error-path xmlns:ns1="http://cisco.com/ns/yang/Cisco-IOS-XR-pbr-cfg" xmlns:ns2="http://cisco.com/ns/yang/Cisco-IOS-XR-ifmgr-cfg">ns2:interface-configurations/ns2:interface-configuration/ns1:pbr</error-path>
+</rpc-error>
+</rpc-reply>
+]]>]]>
+`
+
+	var reply1 Reply
+	if err := Unmarshal([]byte(err1), &reply1); err == nil {
+		t.Fatal("expected an error unmarshalling reply")
+	}
+
+	replyErr := reply1.Error[0]
+	if want := "http://cisco.com/ns/yang/Cisco-IOS-XR-pbr-cfg"; want != replyErr.PathNamespaces["ns1"] {
+		t.Errorf("unexpected path namespace:\nwant:\t%q\ngot:\t%q",
+			want, replyErr.PathNamespaces["ns1"])
+	}
+
+	want := "{http://cisco.com/ns/yang/Cisco-IOS-XR-ifmgr-cfg}interface-configurations/" +
+		"{http://cisco.com/ns/yang/Cisco-IOS-XR-ifmgr-cfg}interface-configuration/" +
+		"{http://cisco.com/ns/yang/Cisco-IOS-XR-pbr-cfg}pbr"
+	if got := replyErr.ResolvedPath(); want != got {
+		t.Errorf("unexpected resolved path:\nwant:\t%q\ngot:\t%q", want, got)
+	}
+
+	noNamespaces := ReplyError{Path: "/config/ns1:pbr"}
+	if want, got := "/config/ns1:pbr", noNamespaces.ResolvedPath(); want != got {
+		t.Errorf("unexpected resolved path:\nwant:\t%q\ngot:\t%q", want, got)
+	}
+}
+
+func TestError_UnmarshalAppTag(t *testing.T) {
+	const err1 = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="102">
+<rpc-error>
+<error-type>application</error-type>
+<error-tag>operation-failed</error-tag>
+<error-severity>error</error-severity>
+<error-app-tag>resource-denied</error-app-tag>
+<error-message>too many interfaces</error-message>
+</rpc-error>
+</rpc-reply>
+]]>]]>
+`
+
+	var reply1 Reply
+	if err := Unmarshal([]byte(err1), &reply1); err == nil {
+		t.Error("expected an error unmarshalling reply")
+	} else if want := "resource-denied"; want != reply1.Error[0].AppTag {
+		t.Errorf("unexpected error app tag:\nwant:\t%q\ngot:\t%q",
+			want, reply1.Error[0].AppTag)
+	} else if want := "netconf: application/operation-failed [resource-denied]: too many interfaces"; want != err.Error() {
+		t.Errorf("unexpected error string:\nwant:\t%q\ngot:\t%q", want, err.Error())
+	}
+}
+
+func TestError_UnmarshalLockDenied(t *testing.T) {
+	// lock-denied example from RFC 6241 section 7.5
+	const err1 = `<rpc-reply message-id="101" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<rpc-error>
+<error-type>protocol</error-type>
+<error-tag>lock-denied</error-tag>
+<error-severity>error</error-severity>
+<error-info>
+<session-id>454</session-id>
+<!-- lock is held by NETCONF session 454 -->
+</error-info>
+</rpc-error>
+</rpc-reply>
+]]>]]>
+`
+
+	var reply1 Reply
+	if err := Unmarshal([]byte(err1), &reply1); err == nil {
+		t.Error("expected an error unmarshalling reply")
+	} else if reply1.Error[0].Tag != ErrorTagLockDenied {
+		t.Errorf("unexpected error tag:\nwant:\t%q\ngot:\t%q",
+			ErrorTagLockDenied, reply1.Error[0].Tag)
+	} else if want := uint(454); want != reply1.Error[0].Info.SessionID {
+		t.Errorf("unexpected error info session-id:\nwant:\t%d\ngot:\t%d",
+			want, reply1.Error[0].Info.SessionID)
+	}
+
+	err := NewDecoder(strings.NewReader(err1)).Decode(&struct{}{})
+
+	var lockErr *LockDeniedError
+	if !errors.As(err, &lockErr) {
+		t.Fatalf("unexpected error type:\nwant:\t%T\ngot:\t%T", lockErr, err)
+	} else if want := uint(454); want != lockErr.SessionID {
+		t.Errorf("unexpected lock holder session-id:\nwant:\t%d\ngot:\t%d", want, lockErr.SessionID)
+	} else if !errors.Is(err, ErrLockDenied) {
+		t.Errorf("errors.Is(%q, %v) returned false", err, ErrLockDenied)
+	} else if !lockErr.Err.IsLockDenied() {
+		t.Errorf("IsLockDenied returned false for %q", lockErr.Err)
+	} else if want := "netconf: protocol/lock-denied (lock held by session 454)"; want != err.Error() {
+		t.Errorf("unexpected error string:\nwant:\t%q\ngot:\t%q", want, err.Error())
 	}
 }
 
@@ -114,23 +225,21 @@ func TestErrorSeverity_UnmarshalText(t *testing.T) {
 		},
 		{
 			ErrorSeverityText: []byte("sadf d error      "),
-			WantErrorSeverity: ErrorSeverityUnknown,
+			WantErrorSeverity: ErrorSeverityZero,
 			WantError:         &UnmarshalTextError{Type: "ErrorSeverity", Value: "sadf d error      "},
 		},
 		{
 			ErrorSeverityText: []byte("errora"),
-			WantErrorSeverity: ErrorSeverityUnknown,
+			WantErrorSeverity: ErrorSeverityZero,
 			WantError:         &UnmarshalTextError{Type: "ErrorSeverity", Value: "errora"},
 		},
 	}
 
 	for i, test := range tests {
 		var es ErrorSeverity
-		if err := es.UnmarshalText(test.ErrorSeverityText); err != nil {
-			if !reflect.DeepEqual(err, test.WantError) {
-				t.Errorf("unexpected error returned from UnmarshalText on test %d\nwant:\t%v\ngot:\t%v",
-					i, test.WantError, err)
-			}
+		if err := es.UnmarshalText(test.ErrorSeverityText); !reflect.DeepEqual(err, test.WantError) {
+			t.Errorf("unexpected error returned from UnmarshalText on test %d\nwant:\t%v\ngot:\t%v",
+				i, test.WantError, err)
 		} else if es != test.WantErrorSeverity {
 			t.Errorf("unexpected ErrorSeverity returned from UnmarshalText on test %d\nwant:\t%q\ngot:\t%q",
 				i, test.WantErrorSeverity, es)
@@ -186,12 +295,12 @@ func TestErrorType_UnmarshalText(t *testing.T) {
 		},
 		{
 			ErrorTypeText: []byte("stransport"),
-			WantErrorType: ErrorTypeUnknown,
+			WantErrorType: ErrorTypeZero,
 			WantError:     &UnmarshalTextError{Type: "ErrorType", Value: "stransport"},
 		},
 		{
 			ErrorTypeText: []byte("  rpcc"),
-			WantErrorType: ErrorTypeUnknown,
+			WantErrorType: ErrorTypeZero,
 			WantError:     &UnmarshalTextError{Type: "ErrorType", Value: "  rpcc"},
 		},
 		{
@@ -202,11 +311,9 @@ func TestErrorType_UnmarshalText(t *testing.T) {
 
 	for i, test := range tests {
 		var et ErrorType
-		if err := et.UnmarshalText(test.ErrorTypeText); err != nil {
-			if !reflect.DeepEqual(err, test.WantError) {
-				t.Errorf("unexpected error returned from UnmarshalText on test %d\nwant:\t%v\ngot:\t%v",
-					i, test.WantError, err)
-			}
+		if err := et.UnmarshalText(test.ErrorTypeText); !reflect.DeepEqual(err, test.WantError) {
+			t.Errorf("unexpected error returned from UnmarshalText on test %d\nwant:\t%v\ngot:\t%v",
+				i, test.WantError, err)
 		} else if et != test.WantErrorType {
 			t.Errorf("unexpected ErrorType returned from UnmarshalText on test %d\nwant:\t%q\ngot:\t%q",
 				i, test.WantErrorType, et)
@@ -263,31 +370,102 @@ func TestErrorTag_UnmarshalText(t *testing.T) {
 		},
 		{
 			ErrorTagText: []byte("ƢƦƴǼ"),
-			WantErrorTag: ErrorTagUnknown,
+			WantErrorTag: ErrorTagZero,
 			WantError:    &UnmarshalTextError{Type: "ErrorTag", Value: "ƢƦƴǼ"},
 		},
 		{
 			ErrorTagText: []byte("    0xDEADBEEFCAFE"),
-			WantErrorTag: ErrorTagUnknown,
+			WantErrorTag: ErrorTagZero,
 			WantError:    &UnmarshalTextError{Type: "ErrorTag", Value: "    0xDEADBEEFCAFE"},
 		},
 		{
 			ErrorTagText: []byte(" i n - u s e "),
-			WantErrorTag: ErrorTagUnknown,
+			WantErrorTag: ErrorTagZero,
 			WantError:    &UnmarshalTextError{Type: "ErrorTag", Value: " i n - u s e "},
 		},
 	}
 
 	for i, test := range tests {
 		var et ErrorTag
-		if err := et.UnmarshalText(test.ErrorTagText); err != nil {
-			if !reflect.DeepEqual(err, test.WantError) {
-				t.Errorf("unexpected error returned from UnmarshalText on test %d\nwant:\t%v\ngot:\t%v",
-					i, test.WantError, err)
-			}
+		if err := et.UnmarshalText(test.ErrorTagText); !reflect.DeepEqual(err, test.WantError) {
+			t.Errorf("unexpected error returned from UnmarshalText on test %d\nwant:\t%v\ngot:\t%v",
+				i, test.WantError, err)
 		} else if et != test.WantErrorTag {
 			t.Errorf("unexpected ErrorTag returned from UnmarshalText on test %d\nwant:\t%q\ngot:\t%q",
 				i, test.WantErrorTag, et)
 		}
 	}
 }
+
+func TestMultiError_Unmarshal(t *testing.T) {
+	const errs = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="103">
+<rpc-error>
+<error-type>application</error-type>
+<error-tag>invalid-value</error-tag>
+<error-severity>error</error-severity>
+<error-message>invalid mtu</error-message>
+</rpc-error>
+<rpc-error>
+<error-type>application</error-type>
+<error-tag>operation-failed</error-tag>
+<error-severity>warning</error-severity>
+<error-message>statement has no effect</error-message>
+</rpc-error>
+<rpc-error>
+<error-type>application</error-type>
+<error-tag>data-missing</error-tag>
+<error-severity>error</error-severity>
+<error-message>interface does not exist</error-message>
+</rpc-error>
+</rpc-reply>
+]]>]]>
+`
+
+	var reply Reply
+	err := Unmarshal([]byte(errs), &reply)
+
+	var multiErr *MultiError
+	if !errors.As(err, &multiErr) {
+		t.Fatalf("unexpected error type:\nwant:\t%T\ngot:\t%T", multiErr, err)
+	} else if got := len(multiErr.Errors()); got != 2 {
+		t.Fatalf("unexpected error count:\nwant:\t%d\ngot:\t%d", 2, got)
+	} else if want := "netconf: application/invalid-value: invalid mtu; netconf: application/data-missing: interface does not exist"; want != err.Error() {
+		t.Errorf("unexpected error string:\nwant:\t%q\ngot:\t%q", want, err.Error())
+	}
+
+	var replyErr *ReplyError
+	if !errors.As(err, &replyErr) {
+		t.Errorf("errors.As could not find a ReplyError in %v", err)
+	} else if replyErr.Tag != ErrorTagInvalidValue {
+		t.Errorf("unexpected error tag:\nwant:\t%q\ngot:\t%q",
+			ErrorTagInvalidValue, replyErr.Tag)
+	}
+}
+
+func TestReplyError_Is(t *testing.T) {
+
+	for tag, sentinel := range errorTagSentinelArray {
+		if sentinel == nil {
+			continue
+		}
+
+		replyErr := &ReplyError{Tag: ErrorTag(tag)}
+		if !errors.Is(replyErr, sentinel) {
+			t.Errorf("errors.Is(%q, %v) returned false", ErrorTag(tag), sentinel)
+		} else if want := "netconf: " + ErrorTag(tag).String(); want != sentinel.Error() {
+			t.Errorf("unexpected sentinel message:\nwant:\t%q\ngot:\t%q", want, sentinel.Error())
+		}
+	}
+
+	if errors.Is(&ReplyError{Tag: ErrorTagInUse}, ErrLockDenied) {
+		t.Errorf("errors.Is(%q, %v) returned true", ErrorTagInUse, ErrLockDenied)
+	}
+
+	multiErr := &MultiError{errs: []*ReplyError{
+		{Tag: ErrorTagInvalidValue},
+		{Tag: ErrorTagDataMissing},
+	}}
+	if !errors.Is(multiErr, ErrDataMissing) {
+		t.Errorf("errors.Is(%v, %v) returned false", multiErr, ErrDataMissing)
+	}
+}
@@ -0,0 +1,102 @@
+package netconf
+
+import (
+	"strings"
+)
+
+const (
+	// VendorJunos identifies Juniper devices to NormalizeVendor.
+	VendorJunos = "junos"
+
+	// VendorCisco identifies Cisco devices to NormalizeVendor.
+	VendorCisco = "cisco"
+)
+
+// messageTags maps phrases found in the messages of errors whose tag
+// was left empty, mostly by Junos, to the tag they imply.
+var messageTags = []struct {
+	phrase string
+	tag    ErrorTag
+}{
+	{"not supported", ErrorTagOpNotSupported},
+	{"unsupported", ErrorTagOpNotSupported},
+	{"database locked", ErrorTagLockDenied},
+	{"locked by", ErrorTagLockDenied},
+	{"lock denied", ErrorTagLockDenied},
+	{"does not exist", ErrorTagDataMissing},
+	{"not found", ErrorTagDataMissing},
+}
+
+// impliedTag returns the error's tag, or the tag implied by its message
+// when the tag is empty, or ErrorTagZero if neither is known.
+func (e *ReplyError) impliedTag() ErrorTag {
+
+	if e.Tag != ErrorTagZero {
+		return e.Tag
+	}
+
+	msg := strings.ToLower(e.Message)
+	for _, mt := range messageTags {
+		if strings.Contains(msg, mt.phrase) {
+			return mt.tag
+		}
+	}
+
+	return ErrorTagZero
+}
+
+// IsNotSupported reports whether the server does not support the
+// operation, either by its operation-not-supported tag, or, when the
+// tag is empty, by its message.
+func (e *ReplyError) IsNotSupported() bool {
+	return e.impliedTag() == ErrorTagOpNotSupported
+}
+
+// IsLockDenied reports whether a lock is held by another session, either
+// by its lock-denied tag, or, when the tag is empty, by its message.
+func (e *ReplyError) IsLockDenied() bool {
+	return e.impliedTag() == ErrorTagLockDenied
+}
+
+// IsDataMissing reports whether the data the operation refers to does not
+// exist, either by its data-missing tag, or, when the tag is empty, by its
+// message.
+func (e *ReplyError) IsDataMissing() bool {
+	return e.impliedTag() == ErrorTagDataMissing
+}
+
+// NormalizeVendor returns a copy of the error with the fields the given
+// vendor (e.g. VendorJunos) is known to leave empty, or to populate
+// differently, filled in with sensible defaults. The error itself is left
+// untouched, so its raw fields are still available.
+//
+// For every vendor, an empty severity becomes ErrorSeverityError. For
+// VendorJunos, an empty type becomes ErrorTypeApplication, and an empty
+// tag is implied from the message, or becomes ErrorTagOpFailed. For
+// VendorCisco, an empty type becomes ErrorTypeApplication, and the prefixes
+// in the path are resolved to their namespaces, like ResolvedPath.
+func (e *ReplyError) NormalizeVendor(vendor string) *ReplyError {
+
+	normalized := *e
+
+	if normalized.Severity == ErrorSeverityZero {
+		normalized.Severity = ErrorSeverityError
+	}
+
+	switch strings.ToLower(vendor) {
+	case VendorJunos:
+		if normalized.Type == ErrorTypeZero {
+			normalized.Type = ErrorTypeApplication
+		}
+		if normalized.Tag = e.impliedTag(); normalized.Tag == ErrorTagZero {
+			normalized.Tag = ErrorTagOpFailed
+		}
+	case VendorCisco:
+		if normalized.Type == ErrorTypeZero {
+			normalized.Type = ErrorTypeApplication
+		}
+		normalized.Path = e.ResolvedPath()
+	}
+
+	return &normalized
+}
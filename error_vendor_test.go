@@ -0,0 +1,63 @@
+package netconf
+
+import (
+	"testing"
+)
+
+func TestReplyError_TagAccessors(t *testing.T) {
+
+	tests := []struct {
+		err                                   ReplyError
+		notSupported, lockDenied, dataMissing bool
+	}{
+		{ReplyError{Tag: ErrorTagOpNotSupported}, true, false, false},
+		{ReplyError{Tag: ErrorTagLockDenied}, false, true, false},
+		{ReplyError{Tag: ErrorTagDataMissing}, false, false, true},
+		{ReplyError{Message: "configuration database locked by:\n  root terminal p0"}, false, true, false},
+		{ReplyError{Message: "statement does not exist"}, false, false, true},
+		{ReplyError{Message: "syntax error"}, false, false, false},
+		// the tag wins over the message
+		{ReplyError{Tag: ErrorTagInUse, Message: "database locked by root"}, false, false, false},
+	}
+
+	for i, test := range tests {
+		if got := test.err.IsNotSupported(); got != test.notSupported {
+			t.Errorf("unexpected IsNotSupported on test %d\nwant:\t%t\ngot:\t%t", i, test.notSupported, got)
+		}
+		if got := test.err.IsLockDenied(); got != test.lockDenied {
+			t.Errorf("unexpected IsLockDenied on test %d\nwant:\t%t\ngot:\t%t", i, test.lockDenied, got)
+		}
+		if got := test.err.IsDataMissing(); got != test.dataMissing {
+			t.Errorf("unexpected IsDataMissing on test %d\nwant:\t%t\ngot:\t%t", i, test.dataMissing, got)
+		}
+	}
+}
+
+func TestReplyError_NormalizeVendor(t *testing.T) {
+
+	junosErr := ReplyError{Message: "syntax error"}
+	normalized := junosErr.NormalizeVendor(VendorJunos)
+
+	if normalized.Type != ErrorTypeApplication || normalized.Tag != ErrorTagOpFailed || normalized.Severity != ErrorSeverityError {
+		t.Errorf("unexpected normalized junos error: %v", normalized)
+	}
+	if junosErr.Type != ErrorTypeZero || junosErr.Tag != ErrorTagZero {
+		t.Errorf("the raw error was modified: %v", &junosErr)
+	}
+
+	ciscoErr := ReplyError{
+		Type:           ErrorTypeProtocol,
+		Path:           "/ns1:pbr",
+		PathNamespaces: map[string]string{"ns1": "http://cisco.com/ns/yang/Cisco-IOS-XR-pbr-cfg"},
+	}
+	normalized = ciscoErr.NormalizeVendor(VendorCisco)
+
+	wantPath := "/{http://cisco.com/ns/yang/Cisco-IOS-XR-pbr-cfg}pbr"
+	if normalized.Path != wantPath {
+		t.Errorf("unexpected normalized path\nwant:\t%q\ngot:\t%q", wantPath, normalized.Path)
+	} else if normalized.Type != ErrorTypeProtocol {
+		t.Errorf("a populated type was replaced\nwant:\t%v\ngot:\t%v", ErrorTypeProtocol, normalized.Type)
+	} else if ciscoErr.Path != "/ns1:pbr" {
+		t.Errorf("the raw path was modified: %q", ciscoErr.Path)
+	}
+}
@@ -0,0 +1,47 @@
+package netconf
+
+import (
+	"context"
+	"fmt"
+)
+
+// ExecAllError is returned by ExecAll when one of the RPCs fails.
+type ExecAllError struct {
+	Index int   // Index is the index of the failed RPC.
+	Err   error // Err is the error returned by the RPC, e.g. a ReplyError.
+}
+
+// Error is ExecAllError's implementation of the error interface.
+func (e *ExecAllError) Error() string {
+	return fmt.Sprintf("netconf: rpc %d failed: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the error returned by the failed RPC.
+func (e *ExecAllError) Unwrap() error {
+	return e.Err
+}
+
+// ExecAll sends every method, decoding each reply into the element of into
+// with the same index, just like Decode. A nil element discards the data.
+//
+// The methods are sent in order, one at a time, and every reply is read
+// before the next method is sent. ExecAll stops at the first failure, or
+// once the context is done, returning an ExecAllError with the index of
+// the method that failed. The replies of the methods before it are fully
+// decoded, and the methods after it are never sent. The session is closed
+// if the context is done while a reply is pending.
+func (s *Session) ExecAll(ctx context.Context, methods []*Method, into []interface{}) error {
+
+	if len(into) != len(methods) {
+		return fmt.Errorf("netconf: %d values given to decode the replies of %d methods",
+			len(into), len(methods))
+	}
+
+	for i, method := range methods {
+		if err := s.exec(ctx, method, into[i]); err != nil {
+			return &ExecAllError{Index: i, Err: err}
+		}
+	}
+
+	return nil
+}
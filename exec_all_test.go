@@ -0,0 +1,55 @@
+package netconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"testing"
+)
+
+func TestSession_ExecAll(t *testing.T) {
+
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		if bytes.Contains(req, []byte("<lock>")) {
+			return []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<rpc-error>
+<error-type>protocol</error-type>
+<error-tag>lock-denied</error-tag>
+<error-severity>error</error-severity>
+</rpc-error>
+</rpc-reply>`)
+		}
+		return []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data><name>router1</name></data></rpc-reply>`)
+	})
+	defer stop()
+
+	type Data struct {
+		XMLName xml.Name `xml:"data"`
+		Name    string   `xml:"name"`
+	}
+
+	var first, second Data
+	methods := []*Method{Get(Filter{}), Get(Filter{})}
+	if err := session.ExecAll(context.Background(), methods, []interface{}{&first, &second}); err != nil {
+		t.Fatal(err)
+	} else if first.Name != "router1" || second.Name != "router1" {
+		t.Errorf("unexpected data\nwant:\t%q, %q\ngot:\t%q, %q", "router1", "router1", first.Name, second.Name)
+	}
+
+	methods = []*Method{Get(Filter{}), Lock(DatastoreCandidate), Get(Filter{})}
+	err := session.ExecAll(context.Background(), methods, make([]interface{}, len(methods)))
+
+	var execErr *ExecAllError
+	if !errors.As(err, &execErr) {
+		t.Fatalf("unexpected error type:\nwant:\t%T\ngot:\t%T", execErr, err)
+	} else if execErr.Index != 1 {
+		t.Errorf("unexpected index\nwant:\t%d\ngot:\t%d", 1, execErr.Index)
+	} else if _, ok := execErr.Err.(*LockDeniedError); !ok {
+		t.Errorf("unexpected wrapped error type:\nwant:\t%T\ngot:\t%T", &LockDeniedError{}, execErr.Err)
+	}
+
+	if err := session.ExecAll(context.Background(), methods, nil); err == nil {
+		t.Error("expected an error when the number of values does not match")
+	}
+}
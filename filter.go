@@ -0,0 +1,134 @@
+package netconf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+)
+
+const (
+	// FilterTypeSubtree identifies a subtree filter, as defined in RFC 6241 section 6.
+	FilterTypeSubtree = "subtree"
+
+	// FilterTypeXPath identifies an XPath filter, which requires the :xpath capability.
+	FilterTypeXPath = "xpath"
+)
+
+// Filter models the filter element of the get and get-config
+// operations. It selects which parts of the datastore are returned
+// by the server.
+type Filter struct {
+	XMLName xml.Name    `xml:"filter"`
+	Type    string      `xml:"type,attr"`
+	Select  string      `xml:"select,attr,omitempty"`
+	Subtree interface{} // Subtree is the subtree filter's content. It must marshal to one or more elements.
+}
+
+// SubtreeFilter returns a Filter with type subtree, containing the
+// given payload. The payload must marshal to one or more XML elements
+// (e.g. a struct with an XMLName field).
+func SubtreeFilter(payload interface{}) Filter {
+	return Filter{
+		Type:    FilterTypeSubtree,
+		Subtree: payload,
+	}
+}
+
+// SubtreeFilterNS is like SubtreeFilter, but the payload's root element
+// is placed in the given default namespace, which is inherited by its
+// unqualified children. It is useful for vendor data models that require
+// their own namespace, without declaring it on every struct.
+func SubtreeFilterNS(namespace string, payload interface{}) Filter {
+	return SubtreeFilter(namespacedPayload{
+		namespace: namespace,
+		payload:   payload,
+	})
+}
+
+// namespacedPayload marshals its payload with the root
+// element in the given default namespace.
+type namespacedPayload struct {
+	namespace string
+	payload   interface{}
+}
+
+// MarshalXML implements the xml.Marshaler interface. The payload is
+// marshaled, and its tokens are re-encoded with the namespace set on
+// the root element when it does not already have one.
+func (np namespacedPayload) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+
+	b, err := xml.Marshal(np.payload)
+	if err != nil {
+		return err
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(b))
+
+	var (
+		depth    int
+		declared bool // the root element declared its own namespace
+	)
+
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				declared = t.Name.Space != "" || hasDefaultNamespace(t.Attr)
+				if !declared {
+					t.Name.Space = np.namespace
+				}
+			}
+			depth++
+			tok = t
+		case xml.EndElement:
+			depth--
+			if depth == 0 && !declared {
+				t.Name.Space = np.namespace
+			}
+			tok = t
+		}
+
+		if err := e.EncodeToken(tok); err != nil {
+			return err
+		}
+	}
+}
+
+// hasDefaultNamespace reports whether the attributes
+// declare a default namespace.
+func hasDefaultNamespace(attrs []xml.Attr) bool {
+	for _, attr := range attrs {
+		if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			return true
+		}
+	}
+	return false
+}
+
+// XPathFilter returns a Filter with type xpath, selecting the nodes
+// matched by the given XPath expression.
+func XPathFilter(expr string) Filter {
+	return Filter{
+		Type:   FilterTypeXPath,
+		Select: expr,
+	}
+}
+
+// Validate checks the filter is supported by the server with the given
+// hello message. An UnsupportedCapabilityError is returned for an XPath
+// filter if the server did not advertise the :xpath capability.
+func (f Filter) Validate(serverHello *HelloMessage) error {
+
+	if f.Type == FilterTypeXPath && !serverHello.HasCapability(CapabilityXPath) {
+		return &UnsupportedCapabilityError{Capability: CapabilityXPath}
+	}
+
+	return nil
+}
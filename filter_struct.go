@@ -0,0 +1,218 @@
+package netconf
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SubtreeFilterFromStruct returns a subtree Filter built from an example
+// struct, usually the zero value of the struct the reply is decoded into,
+// so the filter selects exactly the fields the struct models.
+//
+// The struct's fields are marshaled with their encoding/xml tags, but unlike
+// xml.Marshal, a zero field becomes an empty selection node, which selects
+// the element with all of its content, and a non-zero field becomes a content
+// match node, which only selects siblings of an element with the same value.
+// Nil pointers and empty slices of structs are expanded into their element
+// type, so nested elements are selected without populating the example.
+// A recursive type is only expanded once, and its nested elements of the
+// same type become empty selection nodes.
+//
+// A field tagged omitempty is left out of the filter when it is zero, so it
+// is neither selected nor used for matching. Attributes, character data, and
+// fields tagged "-", innerxml, comment, or any, are ignored.
+func SubtreeFilterFromStruct(v interface{}) Filter {
+	return SubtreeFilter(structFilter{v: v})
+}
+
+// structFilter marshals an example struct into a subtree filter's content.
+type structFilter struct {
+	v interface{}
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+func (sf structFilter) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+
+	rv := reflect.ValueOf(sf.v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			rv = reflect.Zero(rv.Type().Elem())
+			continue
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("netconf: subtree filter example must be a struct, not %s", rv.Type())
+	}
+
+	return encodeFilterValue(e, structName(rv.Type()), rv, make(map[reflect.Type]bool))
+}
+
+// structName returns the element name of a struct, from its XMLName
+// field's tag, or its type name.
+func structName(t reflect.Type) xml.Name {
+	if f, ok := t.FieldByName("XMLName"); ok && f.Type == reflect.TypeOf(xml.Name{}) {
+		if name, _ := parseFilterTag(f.Tag.Get("xml")); name != "" {
+			return splitFilterName(name)
+		}
+	}
+	return xml.Name{Local: t.Name()}
+}
+
+// parseFilterTag returns the name, and the options, of an xml struct tag.
+func parseFilterTag(tag string) (string, []string) {
+	parts := strings.Split(tag, ",")
+	return parts[0], parts[1:]
+}
+
+// splitFilterName splits a tag name in the "namespace local" form.
+func splitFilterName(name string) xml.Name {
+	if i := strings.LastIndexByte(name, ' '); i != -1 {
+		return xml.Name{Space: name[:i], Local: name[i+1:]}
+	}
+	return xml.Name{Local: name}
+}
+
+// encodeFilterValue encodes the value as an element with the given name.
+// Slices are encoded as one element per item, or a single element for
+// the zero item when empty.
+//
+// The struct types being encoded are kept in encoding, so a zero struct
+// whose type is already being encoded, like the expanded element of a
+// Children []Node field, becomes an empty selection node, instead of
+// being expanded forever.
+func encodeFilterValue(e *xml.Encoder, name xml.Name, v reflect.Value, encoding map[reflect.Type]bool) error {
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			if v.Kind() == reflect.Interface {
+				return nil
+			}
+			v = reflect.Zero(v.Type().Elem())
+			continue
+		}
+		v = v.Elem()
+	}
+
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8 {
+		if v.Len() == 0 {
+			return encodeFilterValue(e, name, reflect.Zero(v.Type().Elem()), encoding)
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeFilterValue(e, name, v.Index(i), encoding); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	start := xml.StartElement{Name: name}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch {
+	case v.Kind() == reflect.Struct && encoding[v.Type()] && v.IsZero():
+		// a recursive type, left as a selection node
+	case v.Kind() == reflect.Struct:
+		if !encoding[v.Type()] {
+			encoding[v.Type()] = true
+			defer delete(encoding, v.Type())
+		}
+		if err := encodeFilterFields(e, v, encoding); err != nil {
+			return err
+		}
+	case !v.IsZero():
+		text := fmt.Sprint(v.Interface())
+		if b, ok := v.Interface().([]byte); ok {
+			text = string(b)
+		}
+		if err := e.EncodeToken(xml.CharData(text)); err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// encodeFilterFields encodes the fields of the struct as child elements.
+func encodeFilterFields(e *xml.Encoder, v reflect.Value, encoding map[reflect.Type]bool) error {
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+
+		f := t.Field(i)
+		tag := f.Tag.Get("xml")
+		if f.PkgPath != "" || f.Name == "XMLName" || tag == "-" {
+			continue
+		}
+
+		name, opts := parseFilterTag(tag)
+		fv := v.Field(i)
+
+		if f.Anonymous && name == "" && fv.Kind() == reflect.Struct {
+			if err := encodeFilterFields(e, fv, encoding); err != nil {
+				return err
+			}
+			continue
+		}
+
+		omitEmpty := false
+		skip := false
+		for _, opt := range opts {
+			switch opt {
+			case "omitempty":
+				omitEmpty = true
+			case "attr", "chardata", "cdata", "innerxml", "comment", "any":
+				skip = true
+			}
+		}
+		if skip || (omitEmpty && fv.IsZero()) {
+			continue
+		}
+
+		var elemName xml.Name
+		if name == "" {
+			elemName = xml.Name{Local: f.Name}
+			if elemType := indirectType(f.Type); elemType.Kind() == reflect.Struct {
+				if _, ok := elemType.FieldByName("XMLName"); ok {
+					elemName = structName(elemType)
+				}
+			}
+		} else {
+			elemName = splitFilterName(name)
+		}
+
+		// a path like "a>b>c" nests the field's elements in parents
+		parents := strings.Split(elemName.Local, ">")
+		elemName.Local = parents[len(parents)-1]
+		parents = parents[:len(parents)-1]
+
+		for _, parent := range parents {
+			if err := e.EncodeToken(xml.StartElement{Name: xml.Name{Local: parent}}); err != nil {
+				return err
+			}
+		}
+		if err := encodeFilterValue(e, elemName, fv, encoding); err != nil {
+			return err
+		}
+		for j := len(parents) - 1; j >= 0; j-- {
+			if err := e.EncodeToken(xml.EndElement{Name: xml.Name{Local: parents[j]}}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// indirectType returns the element type of pointers and slices.
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,139 @@
+package netconf
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestFilter_Marshal(t *testing.T) {
+
+	type InterfacesFilter struct {
+		XMLName xml.Name `xml:"interfaces"`
+		Name    string   `xml:"interface>name"`
+	}
+
+	tests := []struct {
+		Filter Filter
+		Want   string
+	}{
+		{
+			Filter: SubtreeFilter(&InterfacesFilter{Name: "ge-0/0/0"}),
+			Want:   `<filter type="subtree"><interfaces><interface><name>ge-0/0/0</name></interface></interfaces></filter>`,
+		},
+		{
+			Filter: XPathFilter("/interfaces/interface[name='ge-0/0/0']"),
+			Want:   `<filter type="xpath" select="/interfaces/interface[name=&#39;ge-0/0/0&#39;]"></filter>`,
+		},
+	}
+
+	for i, test := range tests {
+		if b, err := xml.Marshal(test.Filter); err != nil {
+			t.Errorf("unexpected error marshalling filter on test %d: %v", i, err)
+		} else if got := string(b); test.Want != got {
+			t.Errorf("unexpected filter marshalled on test %d\nwant:\t%q\ngot:\t%q", i, test.Want, got)
+		}
+	}
+}
+
+func TestFilter_Validate(t *testing.T) {
+
+	withXPath := &HelloMessage{Capabilities: []string{
+		"urn:ietf:params:netconf:base:1.1",
+		"urn:ietf:params:netconf:capability:xpath:1.0",
+	}}
+
+	withoutXPath := &HelloMessage{Capabilities: []string{
+		"urn:ietf:params:netconf:base:1.1",
+	}}
+
+	xpathFilter := XPathFilter("/interfaces")
+	if err := xpathFilter.Validate(withXPath); err != nil {
+		t.Errorf("unexpected error validating xpath filter: %v", err)
+	}
+
+	if err := xpathFilter.Validate(withoutXPath); err == nil {
+		t.Error("expected an error validating xpath filter without :xpath")
+	} else if capErr, ok := err.(*UnsupportedCapabilityError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", capErr, err)
+	} else if capErr.Capability != CapabilityXPath {
+		t.Errorf("unexpected capability:\nwant:\t%q\ngot:\t%q", CapabilityXPath, capErr.Capability)
+	}
+
+	if err := SubtreeFilter(nil).Validate(withoutXPath); err != nil {
+		t.Errorf("unexpected error validating subtree filter: %v", err)
+	}
+}
+
+func TestSubtreeFilterFromStruct(t *testing.T) {
+
+	type LLDPNeighbor struct {
+		LocalPortID       string `xml:"lldp-local-port-id"`
+		RemoteSystemName  string `xml:"lldp-remote-system-name"`
+		RemoteChassisType string `xml:"lldp-remote-chassis-id-subtype,omitempty"`
+	}
+
+	// recursive types, which must not be expanded forever
+	type Node struct {
+		XMLName  xml.Name `xml:"node"`
+		Name     string   `xml:"name"`
+		Children []Node   `xml:"children>node"`
+		Parent   *Node    `xml:"parent"`
+	}
+
+	type LLDPReply struct {
+		XMLName   xml.Name       `xml:"lldp-neighbors-information"`
+		Style     string         `xml:"style,attr"`
+		Neighbors []LLDPNeighbor `xml:"lldp-neighbor-information"`
+		Uptime    *int           `xml:"uptime>seconds"`
+	}
+
+	tests := []struct {
+		Example interface{}
+		Want    string
+	}{
+		{
+			Example: &LLDPReply{},
+			Want: `<filter type="subtree"><lldp-neighbors-information>` +
+				`<lldp-neighbor-information><lldp-local-port-id></lldp-local-port-id><lldp-remote-system-name></lldp-remote-system-name></lldp-neighbor-information>` +
+				`<uptime><seconds></seconds></uptime>` +
+				`</lldp-neighbors-information></filter>`,
+		},
+		{
+			Example: &LLDPReply{Neighbors: []LLDPNeighbor{{LocalPortID: "ge-0/0/0"}}},
+			Want: `<filter type="subtree"><lldp-neighbors-information>` +
+				`<lldp-neighbor-information><lldp-local-port-id>ge-0/0/0</lldp-local-port-id><lldp-remote-system-name></lldp-remote-system-name></lldp-neighbor-information>` +
+				`<uptime><seconds></seconds></uptime>` +
+				`</lldp-neighbors-information></filter>`,
+		},
+		{
+			Example: Node{},
+			Want: `<filter type="subtree"><node><name></name>` +
+				`<children><node></node></children><parent></parent>` +
+				`</node></filter>`,
+		},
+		{
+			Example: &Node{Children: []Node{{Name: "leaf"}}},
+			Want: `<filter type="subtree"><node><name></name>` +
+				`<children><node><name>leaf</name><children><node></node></children><parent></parent></node></children><parent></parent>` +
+				`</node></filter>`,
+		},
+		{
+			Example: (*LLDPNeighbor)(nil),
+			Want: `<filter type="subtree"><LLDPNeighbor>` +
+				`<lldp-local-port-id></lldp-local-port-id><lldp-remote-system-name></lldp-remote-system-name>` +
+				`</LLDPNeighbor></filter>`,
+		},
+	}
+
+	for i, test := range tests {
+		if b, err := xml.Marshal(SubtreeFilterFromStruct(test.Example)); err != nil {
+			t.Errorf("unexpected error marshalling filter on test %d: %v", i, err)
+		} else if got := string(b); test.Want != got {
+			t.Errorf("unexpected filter marshalled on test %d\nwant:\t%q\ngot:\t%q", i, test.Want, got)
+		}
+	}
+
+	if _, err := xml.Marshal(SubtreeFilterFromStruct("interfaces")); err == nil {
+		t.Error("expected an error for an example that is not a struct")
+	}
+}
@@ -0,0 +1,172 @@
+package netconf
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+const (
+	// WithDefaultsReportAll reports every data node, including those
+	// set to their schema default values (RFC 6243).
+	WithDefaultsReportAll = "report-all"
+
+	// WithDefaultsReportAllTagged is like WithDefaultsReportAll, but
+	// default values are tagged with a default attribute.
+	WithDefaultsReportAllTagged = "report-all-tagged"
+
+	// WithDefaultsTrim omits data nodes set to their schema default values.
+	WithDefaultsTrim = "trim"
+
+	// WithDefaultsExplicit only reports data nodes explicitly set by a client.
+	WithDefaultsExplicit = "explicit"
+
+	// CapabilityWithDefaults is the capability a server advertises when
+	// it supports the with-defaults parameter.
+	CapabilityWithDefaults = "urn:ietf:params:netconf:capability:with-defaults:1.0"
+)
+
+// getOperation models the get operation, which retrieves
+// running configuration and device state information.
+type getOperation struct {
+	XMLName      xml.Name      `xml:"get"`
+	Filter       *Filter       `xml:",omitempty"`
+	WithDefaults *withDefaults `xml:",omitempty"`
+}
+
+// withDefaults models the with-defaults parameter (RFC 6243).
+type withDefaults struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-with-defaults with-defaults"`
+	Mode    string   `xml:",chardata"`
+}
+
+// Get returns a Method with a get operation, which retrieves the running
+// configuration and device state data selected by the given filter. The
+// zero Filter selects everything.
+//
+// The data in the reply can be decoded by passing a struct modelling
+// the data element to Decode, e.g.:
+//
+//	type Data struct {
+//		XMLName    xml.Name    `xml:"data"`
+//		Interfaces []Interface `xml:"interfaces>interface"`
+//	}
+func Get(filter Filter) *Method {
+	return GetWithDefaults(filter, "")
+}
+
+// GetWithDefaults is like Get, but the server reports default values
+// according to the given with-defaults mode (e.g. WithDefaultsReportAll).
+// The server must advertise CapabilityWithDefaults. An empty mode is
+// omitted, leaving the server's basic mode in effect.
+func GetWithDefaults(filter Filter, mode string) *Method {
+
+	var get getOperation
+
+	if filter.Type != "" {
+		get.Filter = &filter
+	}
+
+	if mode != "" {
+		get.WithDefaults = &withDefaults{Mode: mode}
+	}
+
+	return WrapMethod(&get)
+}
+
+// GetWithDefaultsFor is like GetWithDefaults, but the mode is first checked
+// against the modes the server advertises in its hello message, i.e. the
+// basic-mode and also-supported parameters of CapabilityWithDefaults, e.g.
+//
+//	urn:ietf:params:netconf:capability:with-defaults:1.0?basic-mode=explicit&also-supported=report-all,trim
+//
+// An UnsupportedCapabilityError is returned if the server does not advertise
+// the capability at all, and an UnsupportedOptionError if it does not support
+// the mode, instead of sending an RPC the server would reject. An empty mode
+// is always supported.
+func GetWithDefaultsFor(serverHello *HelloMessage, filter Filter, mode string) (*Method, error) {
+
+	if mode == "" {
+		return GetWithDefaults(filter, mode), nil
+	}
+
+	modes, ok := withDefaultsModes(serverHello)
+	if !ok {
+		return nil, &UnsupportedCapabilityError{Capability: CapabilityWithDefaults}
+	}
+
+	for _, supported := range modes {
+		if supported == mode {
+			return GetWithDefaults(filter, mode), nil
+		}
+	}
+
+	return nil, &UnsupportedOptionError{
+		Option:     "with-defaults",
+		Value:      mode,
+		Capability: CapabilityWithDefaults + "?also-supported=" + mode,
+	}
+}
+
+// withDefaultsModes returns the with-defaults modes advertised by the
+// server, starting with its basic mode, and whether it advertises
+// CapabilityWithDefaults.
+func withDefaultsModes(serverHello *HelloMessage) ([]string, bool) {
+
+	base := strings.TrimSuffix(CapabilityWithDefaults, ":1.0")
+
+	for _, uri := range serverHello.Capabilities {
+
+		c, err := ParseCapability(strings.TrimSpace(uri))
+		if err != nil || c.Base != base {
+			continue
+		}
+
+		var modes []string
+		if basic := c.Params.Get("basic-mode"); basic != "" {
+			modes = append(modes, basic)
+		}
+		for _, mode := range strings.Split(c.Params.Get("also-supported"), ",") {
+			if mode = strings.TrimSpace(mode); mode != "" {
+				modes = append(modes, mode)
+			}
+		}
+
+		return modes, true
+	}
+
+	return nil, false
+}
+
+// getConfigOperation models the get-config operation, which
+// retrieves the configuration of a datastore.
+type getConfigOperation struct {
+	XMLName xml.Name       `xml:"get-config"`
+	Attr    []xml.Attr     `xml:",attr"`
+	Source  datastoreParam `xml:"source"`
+	Filter  *Filter        `xml:",omitempty"`
+}
+
+// GetConfig returns a Method with a get-config operation, which retrieves
+// the configuration selected by the given filter from the source datastore
+// (e.g. DatastoreRunning). The zero Filter selects everything. The data in
+// the reply is decoded like Get's.
+func GetConfig(source string, filter Filter) *Method {
+	return GetConfigNS(source, filter, nil)
+}
+
+// GetConfigNS is like GetConfig, but the given prefixes are declared with
+// xmlns:prefix attributes on the get-config element, so the subtree filter's
+// elements can be qualified by a prefix in their struct tags, like EditConfigNS.
+func GetConfigNS(source string, filter Filter, namespaces map[string]string) *Method {
+
+	getConfig := getConfigOperation{
+		Attr:   namespaceAttrs(namespaces),
+		Source: datastoreParam(source),
+	}
+
+	if filter.Type != "" {
+		getConfig.Filter = &filter
+	}
+
+	return WrapMethod(&getConfig)
+}
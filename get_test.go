@@ -0,0 +1,113 @@
+package netconf
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+
+	type Interfaces struct {
+		XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-interfaces interfaces"`
+	}
+
+	tests := []struct {
+		Method *Method
+		Want   string
+	}{
+		{
+			Method: Get(Filter{}),
+			Want:   `<get></get>`,
+		},
+		{
+			Method: Get(SubtreeFilter(&Interfaces{})),
+			Want:   `<get><filter type="subtree"><interfaces xmlns="urn:ietf:params:xml:ns:yang:ietf-interfaces"></interfaces></filter></get>`,
+		},
+		{
+			Method: Get(XPathFilter("/interfaces")),
+			Want:   `<get><filter type="xpath" select="/interfaces"></filter></get>`,
+		},
+		{
+			Method: GetWithDefaults(Filter{}, WithDefaultsReportAll),
+			Want:   `<get><with-defaults xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-with-defaults">report-all</with-defaults></get>`,
+		},
+		{
+			Method: GetConfig(DatastoreRunning, Filter{}),
+			Want:   `<get-config><source><running></running></source></get-config>`,
+		},
+		{
+			Method: GetConfigNS(DatastoreCandidate, SubtreeFilter(&struct {
+				XMLName xml.Name `xml:"ns1:interface-configurations"`
+			}{}), map[string]string{"ns1": "http://cisco.com/ns/yang/Cisco-IOS-XR-ifmgr-cfg"}),
+			Want: `<get-config xmlns:ns1="http://cisco.com/ns/yang/Cisco-IOS-XR-ifmgr-cfg"><source><candidate></candidate></source><filter type="subtree"><ns1:interface-configurations></ns1:interface-configurations></filter></get-config>`,
+		},
+	}
+
+	for i, test := range tests {
+		if b, err := xml.Marshal(test.Method.Method[0]); err != nil {
+			t.Errorf("test %d: %v", i, err)
+		} else if got := string(b); test.Want != got {
+			t.Errorf("test %d: unexpected bytes encoded\nwant:\t%q\ngot:\t%q", i, test.Want, got)
+		}
+	}
+}
+
+func TestGetWithDefaultsFor(t *testing.T) {
+
+	hello := &HelloMessage{Capabilities: []string{
+		"urn:ietf:params:netconf:base:1.0",
+		CapabilityWithDefaults + "?basic-mode=explicit&also-supported=report-all,trim",
+	}}
+
+	for _, mode := range []string{WithDefaultsExplicit, WithDefaultsReportAll, WithDefaultsTrim, ""} {
+		if method, err := GetWithDefaultsFor(hello, Filter{}, mode); err != nil {
+			t.Errorf("mode %q: %v", mode, err)
+		} else if got := method.Method[0].(*getOperation).WithDefaults; mode != "" && (got == nil || got.Mode != mode) {
+			t.Errorf("mode %q: unexpected with-defaults parameter: %+v", mode, got)
+		}
+	}
+
+	_, err := GetWithDefaultsFor(hello, Filter{}, WithDefaultsReportAllTagged)
+	if optionErr, ok := err.(*UnsupportedOptionError); !ok {
+		t.Fatalf("unexpected error type:\nwant:\t%T\ngot:\t%T", optionErr, err)
+	} else if optionErr.Value != WithDefaultsReportAllTagged {
+		t.Errorf("unexpected option value:\nwant:\t%q\ngot:\t%q", WithDefaultsReportAllTagged, optionErr.Value)
+	}
+
+	_, err = GetWithDefaultsFor(&HelloMessage{}, Filter{}, WithDefaultsReportAll)
+	if capErr, ok := err.(*UnsupportedCapabilityError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", capErr, err)
+	}
+}
+
+func TestGet_Decode(t *testing.T) {
+
+	type Data struct {
+		XMLName xml.Name `xml:"data"`
+		Names   []string `xml:"interfaces>interface>name"`
+	}
+
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		return []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<data>
+<interfaces xmlns="urn:ietf:params:xml:ns:yang:ietf-interfaces">
+<interface><name>eth0</name></interface>
+<interface><name>eth1</name></interface>
+</interfaces>
+</data>
+</rpc-reply>`)
+	})
+	defer stop()
+
+	if err := session.NewEncoder().Encode(Get(Filter{})); err != nil {
+		t.Fatal(err)
+	}
+
+	var data Data
+	if err := session.NewDecoder().Decode(&data); err != nil {
+		t.Fatal(err)
+	} else if len(data.Names) != 2 || data.Names[0] != "eth0" || data.Names[1] != "eth1" {
+		t.Errorf("unexpected interface names decoded\nwant:\t%q\ngot:\t%q",
+			[]string{"eth0", "eth1"}, data.Names)
+	}
+}
@@ -2,25 +2,153 @@ package netconf
 
 import (
 	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
 )
 
+// CapabilityXPath is the capability a server advertises when it
+// supports XPath filters.
+const CapabilityXPath = "urn:ietf:params:netconf:capability:xpath:1.0"
+
+// UnsupportedCapabilityError is returned when an operation requires
+// a capability the server did not advertise.
+type UnsupportedCapabilityError struct {
+	Capability   string   // Capability is the missing capability.
+	Alternatives []string // Alternatives are other missing capabilities, any of which would have done.
+}
+
+// Error is UnsupportedCapabilityError's implementation of the error interface.
+func (e *UnsupportedCapabilityError) Error() string {
+	if len(e.Alternatives) != 0 {
+		return fmt.Sprintf("netconf: server does not support capability %s, nor %s",
+			e.Capability, strings.Join(e.Alternatives, ", nor "))
+	}
+	return fmt.Sprintf("netconf: server does not support capability %s", e.Capability)
+}
+
 // HelloMessage represents a capabilities exchange message.
 type HelloMessage struct {
 	XMLName      xml.Name
-	Capabilities []string `xml:"capabilities>capability"`
-	SessionID    uint     `xml:"session-id,omitempty"`
+	Capabilities []string         `xml:"capabilities>capability"`
+	SessionID    uint             `xml:"session-id,omitempty"`
+	Extensions   []HelloExtension `xml:",any"` // Extensions are any non-standard elements, like vendor session metadata.
+}
+
+// HelloExtension is a non-standard child element of a hello message.
+type HelloExtension struct {
+	XMLName  xml.Name
+	Attr     []xml.Attr `xml:",any,attr"`
+	InnerXML []byte     `xml:",innerxml"`
 }
 
 // Copy makes a deep copy of this HelloMessage.
 func (h *HelloMessage) Copy() *HelloMessage {
-	var c HelloMessage
+
+	c := HelloMessage{
+		XMLName:   h.XMLName,
+		SessionID: h.SessionID,
+	}
+
 	if capLen := len(h.Capabilities); capLen != 0 {
-		c.Capabilities = make([]string, 0, capLen)
+		c.Capabilities = make([]string, capLen)
 		copy(c.Capabilities, h.Capabilities)
 	}
+
+	if extLen := len(h.Extensions); extLen != 0 {
+		c.Extensions = make([]HelloExtension, extLen)
+		for i, ext := range h.Extensions {
+			c.Extensions[i].XMLName = ext.XMLName
+			c.Extensions[i].Attr = append([]xml.Attr(nil), ext.Attr...)
+			c.Extensions[i].InnerXML = append([]byte(nil), ext.InnerXML...)
+		}
+	}
+
 	return &c
 }
 
+// HasCapability reports whether the hello message advertises the
+// given capability. Any parameters following a "?" in an advertised
+// capability are ignored. A nil hello message advertises nothing.
+func (h *HelloMessage) HasCapability(capability string) bool {
+
+	if h == nil {
+		return false
+	}
+
+	for _, c := range h.Capabilities {
+		if i := strings.IndexByte(c, '?'); i != -1 {
+			c = c[:i]
+		}
+		if strings.TrimSpace(c) == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// baseCapability is the base protocol capability, without its version.
+const baseCapability = "urn:ietf:params:netconf:base"
+
+// BaseVersions returns the versions of the base protocol the hello message
+// advertises, e.g. "1.0" for urn:ietf:params:netconf:base:1.0, in ascending
+// order, and without duplicates. Capabilities that can't be parsed are
+// ignored.
+func (h *HelloMessage) BaseVersions() []string {
+
+	var versions []string
+	for _, c := range h.Capabilities {
+
+		capability, err := ParseCapability(strings.TrimSpace(c))
+		if err != nil || capability.Base != baseCapability {
+			continue
+		}
+
+		i := sort.Search(len(versions), func(i int) bool {
+			return !versionLess(versions[i], capability.Version)
+		})
+		if i < len(versions) && versions[i] == capability.Version {
+			continue
+		}
+		versions = append(versions, "")
+		copy(versions[i+1:], versions[i:])
+		versions[i] = capability.Version
+	}
+
+	return versions
+}
+
+// PreferredBase returns the highest version of the base protocol the hello
+// message advertises, e.g. "1.1" when both 1.0 and 1.1 are advertised, or
+// an empty string when none is. It does not select the framing used by the
+// session, which is always the base:1.0 end-of-message framing.
+func (h *HelloMessage) PreferredBase() string {
+	if versions := h.BaseVersions(); len(versions) != 0 {
+		return versions[len(versions)-1]
+	}
+	return ""
+}
+
+// versionLess reports whether the version a, like "1.0", is lower
+// than the version b, comparing each dot separated part numerically.
+func versionLess(a, b string) bool {
+
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+
+		// versions are made of digits, so the longer part is higher
+		aPart := strings.TrimLeft(aParts[i], "0")
+		bPart := strings.TrimLeft(bParts[i], "0")
+		if len(aPart) != len(bPart) {
+			return len(aPart) < len(bPart)
+		} else if aPart != bPart {
+			return aPart < bPart
+		}
+	}
+
+	return len(aParts) < len(bParts)
+}
+
 // DefaultHelloMessage is this library's default hello sent to the
 // server, when it is not sent manually by the client application.
 const DefaultHelloMessage = `<?xml version="1.0" encoding="UTF-8"?>
@@ -31,3 +159,54 @@ const DefaultHelloMessage = `<?xml version="1.0" encoding="UTF-8"?>
 </hello>
 ]]>]]>
 `
+
+// baseCapabilityPrefix prefixes the base protocol capabilities, one of which
+// every hello message must advertise.
+const baseCapabilityPrefix = netconfCapabilityPrefix + "base:"
+
+// MissingBaseCapabilityError is returned when the client capabilities to
+// advertise in the hello do not include a base protocol capability, e.g.
+// urn:ietf:params:netconf:base:1.1, without which the server must close
+// the session.
+type MissingBaseCapabilityError struct {
+	Capabilities []string // Capabilities are the client capabilities.
+}
+
+// Error is MissingBaseCapabilityError's implementation of the error interface.
+func (e *MissingBaseCapabilityError) Error() string {
+	return fmt.Sprintf("netconf: no base capability in client capabilities %q", e.Capabilities)
+}
+
+// clientHelloMessage returns the hello advertising the given capabilities,
+// followed by a message separator, or DefaultHelloMessage if there are none.
+// A MissingBaseCapabilityError is returned if none is a base capability.
+func clientHelloMessage(capabilities []string) ([]byte, error) {
+
+	if len(capabilities) == 0 {
+		return []byte(DefaultHelloMessage), nil
+	}
+
+	hasBase := false
+	for _, c := range capabilities {
+		if strings.HasPrefix(c, baseCapabilityPrefix) {
+			hasBase = true
+			break
+		}
+	}
+
+	if !hasBase {
+		return nil, &MissingBaseCapabilityError{Capabilities: capabilities}
+	}
+
+	hello := HelloMessage{
+		XMLName:      xml.Name{Space: BaseNamespace, Local: "hello"},
+		Capabilities: capabilities,
+	}
+
+	b, err := xml.Marshal(&hello)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(xml.Header + string(b) + "\n" + MessageSeparator + "\n"), nil
+}
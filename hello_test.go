@@ -0,0 +1,221 @@
+package netconf
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHelloMessage_Extensions(t *testing.T) {
+
+	helloWithExtension := `<?xml version="1.0" encoding="UTF-8"?>
+<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<capabilities>
+<capability>urn:ietf:params:netconf:base:1.0</capability>
+</capabilities>
+<session-id>42</session-id>
+<platform-info xmlns="http://example.com/ns/vendor" version="2"><model>srx240</model></platform-info>
+</hello>
+]]>]]>
+`
+
+	want := []HelloExtension{
+		{
+			XMLName: xml.Name{Space: "http://example.com/ns/vendor", Local: "platform-info"},
+			Attr: []xml.Attr{
+				{Name: xml.Name{Local: "xmlns"}, Value: "http://example.com/ns/vendor"},
+				{Name: xml.Name{Local: "version"}, Value: "2"},
+			},
+			InnerXML: []byte("<model>srx240</model>"),
+		},
+	}
+
+	var hello HelloMessage
+	if err := NewDecoder(strings.NewReader(helloWithExtension)).DecodeHello(&hello); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(want, hello.Extensions) {
+		t.Errorf("unexpected hello extensions\nwant:\t%v\ngot:\t%v", want, hello.Extensions)
+	}
+
+	c := hello.Copy()
+	if !reflect.DeepEqual(&hello, c) {
+		t.Errorf("copy does not match original\nwant:\t%v\ngot:\t%v", &hello, c)
+	}
+
+	c.Extensions[0].InnerXML[1] = 'x'
+	c.Capabilities[0] = "modified"
+	if !reflect.DeepEqual(want, hello.Extensions) {
+		t.Errorf("modifying copy modified original extensions: %v", hello.Extensions)
+	} else if hello.Capabilities[0] == "modified" {
+		t.Error("modifying copy modified original capabilities")
+	}
+}
+
+func TestSession_HelloTimeout(t *testing.T) {
+
+	// the server accepts the connection, but never sends its hello
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	go func() { _, _ = io.Copy(io.Discard, serverConn) }()
+
+	var session Session
+	session.attach(clientConn, clientConn, nil)
+
+	timeout := 50 * time.Millisecond
+	_, err := session.exchangeHelloTimeout(timeout, nil)
+
+	var helloTimeoutErr *HelloTimeoutError
+	if !errors.As(err, &helloTimeoutErr) {
+		t.Fatalf("unexpected error:\nwant:\t%T\ngot:\t%v", helloTimeoutErr, err)
+	} else if helloTimeoutErr.Timeout != timeout {
+		t.Errorf("unexpected timeout:\nwant:\t%s\ngot:\t%s", timeout, helloTimeoutErr.Timeout)
+	}
+}
+
+func TestSession_HelloTimeout_NotReached(t *testing.T) {
+
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	go func() {
+		_, _ = io.WriteString(serverConn, TestServerHello)
+		_, _ = io.Copy(io.Discard, serverConn)
+	}()
+
+	var session Session
+	session.attach(clientConn, clientConn, nil)
+	defer session.Close()
+
+	helloMessage, err := session.exchangeHelloTimeout(time.Minute, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if helloMessage.SessionID != 1 {
+		t.Errorf("unexpected session-id:\nwant:\t%d\ngot:\t%d", 1, helloMessage.SessionID)
+	}
+}
+
+func TestSession_ClientCapabilities(t *testing.T) {
+
+	tests := []struct {
+		Capabilities []string
+		Want         []string
+	}{
+		{
+			Capabilities: nil,
+			Want:         []string{"urn:ietf:params:netconf:base:1.1"},
+		},
+		{
+			Capabilities: []string{
+				"urn:ietf:params:netconf:base:1.0",
+				"urn:ietf:params:netconf:capability:interleave:1.0",
+			},
+			Want: []string{
+				"urn:ietf:params:netconf:base:1.0",
+				"urn:ietf:params:netconf:capability:interleave:1.0",
+			},
+		},
+	}
+
+	for i, test := range tests {
+
+		clientConn, serverConn := net.Pipe()
+
+		// the server decodes the client's hello after sending its own
+		helloCh := make(chan HelloMessage, 1)
+		go func() {
+			defer serverConn.Close()
+			_, _ = io.WriteString(serverConn, TestServerHello)
+			var clientHello HelloMessage
+			_ = NewDecoder(serverConn).DecodeHello(&clientHello)
+			helloCh <- clientHello
+		}()
+
+		var session Session
+		session.attach(clientConn, clientConn, nil)
+
+		_, err := session.exchangeHelloTimeout(0, test.Capabilities)
+		clientHello := <-helloCh
+		_ = session.Close()
+
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+		} else if clientHello.XMLName.Space != BaseNamespace {
+			t.Errorf("test %d: unexpected hello namespace\nwant:\t%q\ngot:\t%q", i, BaseNamespace, clientHello.XMLName.Space)
+		} else if !reflect.DeepEqual(test.Want, clientHello.Capabilities) {
+			t.Errorf("test %d: unexpected capabilities on the wire\nwant:\t%q\ngot:\t%q", i, test.Want, clientHello.Capabilities)
+		}
+	}
+}
+
+func TestSession_ClientCapabilities_NoBase(t *testing.T) {
+
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+
+	var session Session
+	session.attach(clientConn, clientConn, nil)
+	defer session.Close()
+
+	// nothing is read nor written, so the exchange would
+	// block on the pipe if the hello was not rejected first
+	_, err := session.exchangeHelloTimeout(0, []string{"urn:ietf:params:netconf:capability:interleave:1.0"})
+
+	var baseErr *MissingBaseCapabilityError
+	if !errors.As(err, &baseErr) {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", baseErr, err)
+	}
+}
+
+func TestHelloMessage_BaseVersions(t *testing.T) {
+
+	tests := []struct {
+		Capabilities []string
+		Versions     []string
+		Preferred    string
+	}{
+		{
+			Capabilities: []string{"urn:ietf:params:netconf:base:1.0", CapabilityXPath},
+			Versions:     []string{"1.0"},
+			Preferred:    "1.0",
+		},
+		{
+			Capabilities: []string{"urn:ietf:params:netconf:base:1.1"},
+			Versions:     []string{"1.1"},
+			Preferred:    "1.1",
+		},
+		{
+			Capabilities: []string{
+				" urn:ietf:params:netconf:base:1.1\n",
+				"urn:ietf:params:netconf:capability:candidate:1.0",
+				"urn:ietf:params:netconf:base:1.0",
+				"urn:ietf:params:netconf:base:1.1",
+			},
+			Versions:  []string{"1.0", "1.1"},
+			Preferred: "1.1",
+		},
+		{
+			Capabilities: []string{"urn:ietf:params:netconf:base:1.10", "urn:ietf:params:netconf:base:1.9"},
+			Versions:     []string{"1.9", "1.10"},
+			Preferred:    "1.10",
+		},
+		{
+			Capabilities: []string{"urn:ietf:params:netconf:base", CapabilityXPath},
+		},
+	}
+
+	for i, test := range tests {
+
+		hello := HelloMessage{Capabilities: test.Capabilities}
+
+		if got := hello.BaseVersions(); !reflect.DeepEqual(test.Versions, got) {
+			t.Errorf("test %d: unexpected base versions\nwant:\t%q\ngot:\t%q", i, test.Versions, got)
+		}
+		if got := hello.PreferredBase(); test.Preferred != got {
+			t.Errorf("test %d: unexpected preferred base\nwant:\t%q\ngot:\t%q", i, test.Preferred, got)
+		}
+	}
+}
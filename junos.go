@@ -0,0 +1,277 @@
+package netconf
+
+import (
+	"context"
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// JunosFormatText requests a command's output as the text shown by the CLI.
+	JunosFormatText = "text"
+
+	// JunosFormatXML requests a command's output as structured XML.
+	JunosFormatXML = "xml"
+)
+
+// junosCommand models the Junos command RPC, which runs
+// an operational mode CLI command.
+type junosCommand struct {
+	XMLName xml.Name `xml:"command"`
+	Format  string   `xml:"format,attr,omitempty"`
+	Command string   `xml:",chardata"`
+}
+
+// JunosCommand returns a Method that runs the given operational
+// mode CLI command (e.g. "show version") on a Junos device. The
+// format is usually JunosFormatText or JunosFormatXML, and the
+// device's default (XML) is used when it is empty.
+//
+// Replies to the text format can be decoded into a JunosOutput.
+func JunosCommand(cmd, format string) *Method {
+	return WrapMethod(&junosCommand{
+		Format:  format,
+		Command: cmd,
+	})
+}
+
+// JunosOutput models the output element returned by a Junos
+// command RPC in the text format. Its Text preserves the
+// newlines and spacing of the CLI output.
+type JunosOutput struct {
+	XMLName xml.Name `xml:"output"`
+	Text    string   `xml:",chardata"`
+}
+
+// junosLoadConfiguration models the Junos load-configuration RPC,
+// which loads a previous configuration into the candidate.
+type junosLoadConfiguration struct {
+	XMLName  xml.Name `xml:"load-configuration"`
+	Rollback string   `xml:"rollback,attr"`
+}
+
+// junosGetRollbackInformation models the Junos
+// get-rollback-information RPC.
+type junosGetRollbackInformation struct {
+	XMLName  xml.Name `xml:"get-rollback-information"`
+	Rollback int      `xml:"rollback"`
+	Compare  int      `xml:"compare"`
+}
+
+// JunosRollback returns a Method that loads the rollback configuration
+// with the given index (0 is the active configuration) into the candidate
+// on a Junos device. The candidate must be committed for it to take effect.
+func JunosRollback(n int) *Method {
+	return WrapMethod(&junosLoadConfiguration{
+		Rollback: strconv.Itoa(n),
+	})
+}
+
+// JunosRollbackInfo returns a Method that gets the differences between
+// the rollback configuration with the given index and the active
+// configuration of a Junos device, like "show system rollback n compare 0".
+//
+// The reply can be decoded into a JunosRollbackInformation.
+func JunosRollbackInfo(n int) *Method {
+	return WrapMethod(&junosGetRollbackInformation{
+		Rollback: n,
+	})
+}
+
+// JunosRollbackInformation models the reply to a JunosRollbackInfo RPC.
+// Diff is the text output in the patch format shown by the CLI, e.g.
+//
+//	[edit system]
+//	-  host-name srx240;
+//	+  host-name srx240-lab;
+type JunosRollbackInformation struct {
+	XMLName xml.Name `xml:"rollback-information"`
+	Diff    string   `xml:"configuration-information>configuration-output"`
+}
+
+// junosCommitConfiguration models the Junos commit-configuration RPC.
+type junosCommitConfiguration struct {
+	XMLName        xml.Name  `xml:"commit-configuration"`
+	Check          *struct{} `xml:"check,omitempty"`
+	Synchronize    *struct{} `xml:"synchronize,omitempty"`
+	Confirmed      *struct{} `xml:"confirmed,omitempty"`
+	ConfirmTimeout uint      `xml:"confirm-timeout,omitempty"`
+	Log            string    `xml:"log,omitempty"`
+}
+
+// JunosCommitOptions are the options of a JunosCommit.
+type JunosCommitOptions struct {
+	// Log is a comment recorded with the commit, shown by
+	// "show system commit", for auditing.
+	Log string
+
+	// Confirmed makes the device roll back the commit unless another
+	// commit confirms it within ConfirmTimeout.
+	Confirmed bool
+
+	// ConfirmTimeout is the time a confirmed commit has to be confirmed.
+	// Junos counts it in minutes, so it is rounded down to minutes, and
+	// the device's default of 10 minutes is used when it is less than a
+	// minute. It is ignored unless Confirmed is set.
+	ConfirmTimeout time.Duration
+
+	// Synchronize commits on both routing engines of a dual routing
+	// engine device, or on every member of a virtual chassis.
+	Synchronize bool
+
+	// Check only checks the candidate's syntax and semantics,
+	// without committing it.
+	Check bool
+}
+
+// JunosCommit returns a Method that commits the candidate configuration of
+// a Junos device with the commit-configuration RPC, which, unlike Commit,
+// supports a log comment, and the device's own confirmed commit, counted in
+// minutes, and synchronize semantics.
+//
+// The reply can be decoded into a JunosCommitResults.
+func JunosCommit(opts JunosCommitOptions) *Method {
+
+	commit := junosCommitConfiguration{
+		Log: opts.Log,
+	}
+
+	if opts.Check {
+		commit.Check = &struct{}{}
+	}
+	if opts.Synchronize {
+		commit.Synchronize = &struct{}{}
+	}
+	if opts.Confirmed {
+		commit.Confirmed = &struct{}{}
+		commit.ConfirmTimeout = uint(opts.ConfirmTimeout / time.Minute)
+	}
+
+	return WrapMethod(&commit)
+}
+
+// JunosCommitResults models the reply to a JunosCommit RPC. Warnings
+// holds the warnings the device reported while committing, e.g. for
+// statements it ignored, which don't make the commit fail.
+type JunosCommitResults struct {
+	XMLName        xml.Name             `xml:"commit-results"`
+	RoutingEngines []JunosRoutingEngine `xml:"routing-engine"`
+	Warnings       []ReplyError         `xml:"rpc-error"`
+}
+
+// JunosRoutingEngine models the commit result of one routing engine.
+type JunosRoutingEngine struct {
+	Name string `xml:"name"`
+
+	// CommitSuccess is set when the configuration was committed.
+	CommitSuccess *struct{} `xml:"commit-success"`
+
+	// CheckSuccess is set when the configuration passed a commit check.
+	CheckSuccess *struct{} `xml:"commit-check-success"`
+}
+
+// Success reports whether every routing engine committed, or checked,
+// the configuration successfully.
+func (r *JunosCommitResults) Success() bool {
+
+	if len(r.RoutingEngines) == 0 {
+		return false
+	}
+
+	for _, re := range r.RoutingEngines {
+		if re.CommitSuccess == nil && re.CheckSuccess == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// junosGetCommitInformation models the Junos get-commit-information RPC,
+// which returns the commit history, like "show system commit".
+type junosGetCommitInformation struct {
+	XMLName xml.Name `xml:"get-commit-information"`
+}
+
+// junosCommitInformation models the reply to get-commit-information,
+// holding the most recent commit first.
+type junosCommitInformation struct {
+	XMLName xml.Name             `xml:"commit-information"`
+	History []junosCommitHistory `xml:"commit-history"`
+}
+
+// junosCommitHistory models a commit in the commit history.
+type junosCommitHistory struct {
+	SequenceNumber int    `xml:"sequence-number"`
+	Comment        string `xml:"comment"`
+	DateTime       struct {
+		Seconds int64 `xml:"seconds,attr"` // Seconds is the commit's Unix time.
+	} `xml:"date-time"`
+}
+
+// JunosPendingConfirmedCommit reports whether a confirmed commit is waiting
+// to be confirmed on a Junos device, and, if it is, the time remaining before
+// the device rolls it back. It is read from the commit history returned by
+// the get-commit-information RPC, because NETCONF itself, including the
+// netconf-state monitoring data, does not expose it.
+//
+// The timeout is read from the comment Junos adds to a confirmed commit,
+// e.g. "commit confirmed, rollback in 10mins", which is kept apart from
+// the log message given with the commit. The remaining time is computed
+// from the commit's time and its timeout, which Junos counts in minutes,
+// so it is as accurate as the clocks of the device and of the client are
+// synchronized. Other servers reply to the RPC with an error, which is
+// returned.
+func (s *Session) JunosPendingConfirmedCommit(ctx context.Context) (bool, time.Duration, error) {
+
+	var info junosCommitInformation
+	if err := s.exec(ctx, WrapMethod(&junosGetCommitInformation{}), &info); err != nil {
+		return false, 0, err
+	}
+
+	// a commit confirming a confirmed commit is a commit of its own,
+	// so only the most recent commit can be pending
+	if len(info.History) == 0 {
+		return false, 0, nil
+	}
+
+	timeout, ok := junosConfirmTimeout(info.History[0].Comment)
+	if !ok || info.History[0].DateTime.Seconds == 0 {
+		return false, 0, nil
+	}
+
+	committed := time.Unix(info.History[0].DateTime.Seconds, 0)
+	remaining := time.Until(committed.Add(timeout))
+	if remaining <= 0 {
+		return false, 0, nil
+	}
+
+	return true, remaining, nil
+}
+
+// junosConfirmTimeout returns the timeout of a confirmed commit, from
+// the comment Junos adds to it in the commit history, e.g.
+// "commit confirmed, rollback in 10mins".
+func junosConfirmTimeout(comment string) (time.Duration, bool) {
+
+	const marker = "rollback in "
+
+	i := strings.Index(comment, marker)
+	if !strings.Contains(comment, "commit confirmed") || i == -1 {
+		return 0, false
+	}
+
+	digits := comment[i+len(marker):]
+	if j := strings.IndexFunc(digits, func(r rune) bool { return r < '0' || r > '9' }); j != -1 {
+		digits = digits[:j]
+	}
+
+	minutes, err := strconv.Atoi(digits)
+	if err != nil {
+		return 0, false
+	}
+
+	return time.Duration(minutes) * time.Minute, true
+}
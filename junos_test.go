@@ -0,0 +1,252 @@
+package netconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestJunosCommand(t *testing.T) {
+
+	method := JunosCommand("show system uptime", JunosFormatText)
+
+	b, err := Marshal(method)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := fmt.Sprintf(`<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="%s"><command format="text">show system uptime</command></rpc>]]>]]>
+`, method.Attr[0].Value)
+
+	if got := string(b); want != got {
+		t.Errorf("unexpected bytes encoded\nwant:\t%q\ngot:\t%q", want, got)
+	}
+}
+
+func TestJunosOutput_Unmarshal(t *testing.T) {
+
+	replyBytes := []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/15.1X49/junos">
+<output>
+Current time: 2017-03-01 10:15:32 PST
+System booted: 2017-02-27 08:01:10 PST (2d 02:14 ago)
+</output>
+</rpc-reply>
+]]>]]>
+`)
+
+	want := `
+Current time: 2017-03-01 10:15:32 PST
+System booted: 2017-02-27 08:01:10 PST (2d 02:14 ago)
+`
+
+	var output JunosOutput
+	if err := NewDecoder(bytes.NewReader(replyBytes)).Decode(&output); err != nil {
+		t.Fatal(err)
+	} else if want != output.Text {
+		t.Errorf("unexpected output text\nwant:\t%q\ngot:\t%q", want, output.Text)
+	}
+}
+
+func TestJunosRollback(t *testing.T) {
+
+	tests := []struct {
+		Method *Method
+		Want   string
+	}{
+		{
+			Method: JunosRollback(1),
+			Want:   `<load-configuration rollback="1"></load-configuration>`,
+		},
+		{
+			Method: JunosRollbackInfo(2),
+			Want:   `<get-rollback-information><rollback>2</rollback><compare>0</compare></get-rollback-information>`,
+		},
+	}
+
+	for i, test := range tests {
+		if b, err := xml.Marshal(test.Method.Method[0]); err != nil {
+			t.Errorf("test %d: %v", i, err)
+		} else if got := string(b); test.Want != got {
+			t.Errorf("test %d: unexpected bytes encoded\nwant:\t%q\ngot:\t%q", i, test.Want, got)
+		}
+	}
+}
+
+func TestJunosRollbackInformation_Unmarshal(t *testing.T) {
+
+	replyBytes := []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/15.1X49/junos">
+<rollback-information>
+<ok/>
+<configuration-information>
+<configuration-output>
+[edit system]
+-  host-name srx240;
++  host-name srx240-lab;
+</configuration-output>
+</configuration-information>
+</rollback-information>
+</rpc-reply>
+]]>]]>
+`)
+
+	want := `
+[edit system]
+-  host-name srx240;
++  host-name srx240-lab;
+`
+
+	var info JunosRollbackInformation
+	if err := NewDecoder(bytes.NewReader(replyBytes)).Decode(&info); err != nil {
+		t.Fatal(err)
+	} else if want != info.Diff {
+		t.Errorf("unexpected rollback diff\nwant:\t%q\ngot:\t%q", want, info.Diff)
+	}
+}
+
+func TestJunosCommit(t *testing.T) {
+
+	tests := []struct {
+		Options JunosCommitOptions
+		Want    string
+	}{
+		{
+			Want: `<commit-configuration></commit-configuration>`,
+		},
+		{
+			Options: JunosCommitOptions{Log: "ticket 42", Synchronize: true},
+			Want:    `<commit-configuration><synchronize></synchronize><log>ticket 42</log></commit-configuration>`,
+		},
+		{
+			Options: JunosCommitOptions{Confirmed: true, ConfirmTimeout: 5*time.Minute + 30*time.Second},
+			Want:    `<commit-configuration><confirmed></confirmed><confirm-timeout>5</confirm-timeout></commit-configuration>`,
+		},
+		{
+			Options: JunosCommitOptions{Check: true, ConfirmTimeout: time.Hour},
+			Want:    `<commit-configuration><check></check></commit-configuration>`,
+		},
+	}
+
+	for i, test := range tests {
+		if b, err := xml.Marshal(JunosCommit(test.Options).Method[0]); err != nil {
+			t.Errorf("test %d: %v", i, err)
+		} else if got := string(b); test.Want != got {
+			t.Errorf("test %d: unexpected bytes encoded\nwant:\t%q\ngot:\t%q", i, test.Want, got)
+		}
+	}
+}
+
+func TestJunosCommitResults_Unmarshal(t *testing.T) {
+
+	replyBytes := []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/15.1X49/junos">
+<commit-results>
+<rpc-error>
+<error-type>protocol</error-type>
+<error-tag>operation-failed</error-tag>
+<error-severity>warning</error-severity>
+<error-message>mgd: statement has no contents; ignored</error-message>
+</rpc-error>
+<routing-engine junos:style="normal">
+<name>re0</name>
+<commit-success/>
+</routing-engine>
+</commit-results>
+</rpc-reply>
+]]>]]>
+`)
+
+	var results JunosCommitResults
+	if err := NewDecoder(bytes.NewReader(replyBytes)).Decode(&results); err != nil {
+		t.Fatal(err)
+	}
+
+	if !results.Success() {
+		t.Errorf("unexpected commit results: %+v", results)
+	}
+
+	if len(results.Warnings) != 1 {
+		t.Fatalf("unexpected warning count\nwant:\t%d\ngot:\t%d", 1, len(results.Warnings))
+	}
+
+	want := "mgd: statement has no contents; ignored"
+	if got := results.Warnings[0].Message; want != got {
+		t.Errorf("unexpected warning message\nwant:\t%q\ngot:\t%q", want, got)
+	}
+}
+
+func TestSession_JunosPendingConfirmedCommit(t *testing.T) {
+
+	commitInformation := func(seconds int64, log, comment string) []byte {
+		if log != "" {
+			log = "\n<log>" + log + "</log>"
+		}
+		return []byte(fmt.Sprintf(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/15.1X49/junos">
+<commit-information>
+<commit-history>
+<sequence-number>0</sequence-number>
+<user>admin</user>
+<client>netconf</client>
+<date-time junos:seconds="%d">2017-03-01 10:15:32 PST</date-time>%s
+<comment>%s</comment>
+</commit-history>
+<commit-history>
+<sequence-number>1</sequence-number>
+<user>admin</user>
+<client>cli</client>
+<date-time junos:seconds="1488391532">2017-03-01 10:05:32 PST</date-time>
+</commit-history>
+</commit-information>
+</rpc-reply>`, seconds, log, comment))
+	}
+
+	twoMinutesAgo := time.Now().Add(-2 * time.Minute).Unix()
+
+	tests := []struct {
+		Reply   []byte
+		Pending bool
+		Min     time.Duration
+		Max     time.Duration
+	}{
+		{
+			Reply:   commitInformation(twoMinutesAgo, "", "commit confirmed, rollback in 10mins"),
+			Pending: true,
+			Min:     8*time.Minute - 5*time.Second,
+			Max:     8 * time.Minute,
+		},
+		{
+			// the user's log message is not the timeout's source
+			Reply:   commitInformation(twoMinutesAgo, "ticket 42, rollback in 1mins if the uplink drops", "commit confirmed, rollback in 10mins"),
+			Pending: true,
+			Min:     8*time.Minute - 5*time.Second,
+			Max:     8 * time.Minute,
+		},
+		{
+			// the timeout expired, so the commit was rolled back
+			Reply: commitInformation(twoMinutesAgo, "", "commit confirmed, rollback in 1mins"),
+		},
+		{
+			// confirmed by a later commit, logged like a confirmed one
+			Reply: commitInformation(twoMinutesAgo, "commit confirmed, rollback in 10mins", ""),
+		},
+	}
+
+	for i, test := range tests {
+
+		session, stop := newTestSession(t, func(req []byte) []byte {
+			return test.Reply
+		})
+
+		pending, remaining, err := session.JunosPendingConfirmedCommit(context.Background())
+		stop()
+
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+		} else if pending != test.Pending {
+			t.Errorf("test %d: unexpected pending\nwant:\t%t\ngot:\t%t", i, test.Pending, pending)
+		} else if remaining < test.Min || remaining > test.Max {
+			t.Errorf("test %d: unexpected remaining time\nwant:\t%s to %s\ngot:\t%s", i, test.Min, test.Max, remaining)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package netconf
+
+import (
+	"encoding/xml"
+)
+
+// datastoreParam marshals a datastore parameter of an operation,
+// like target or source, holding an empty element named after the
+// datastore, e.g. <target><candidate/></target>.
+type datastoreParam string
+
+// MarshalXML implements the xml.Marshaler interface.
+func (d datastoreParam) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+
+	datastore := xml.StartElement{Name: xml.Name{Local: string(d)}}
+
+	for _, tok := range []xml.Token{start, datastore, datastore.End(), start.End()} {
+		if err := e.EncodeToken(tok); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lockOperation models the lock operation.
+type lockOperation struct {
+	XMLName xml.Name       `xml:"lock"`
+	Target  datastoreParam `xml:"target"`
+}
+
+// unlockOperation models the unlock operation.
+type unlockOperation struct {
+	XMLName xml.Name       `xml:"unlock"`
+	Target  datastoreParam `xml:"target"`
+}
+
+// Lock returns a Method that locks the given datastore (e.g.
+// DatastoreCandidate), so other sessions can't modify it until
+// it is released with Unlock, or the session is closed.
+func Lock(target string) *Method {
+	return WrapMethod(&lockOperation{Target: datastoreParam(target)})
+}
+
+// Unlock returns a Method that releases a lock on the given
+// datastore, obtained with Lock.
+func Unlock(target string) *Method {
+	return WrapMethod(&unlockOperation{Target: datastoreParam(target)})
+}
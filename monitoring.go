@@ -0,0 +1,99 @@
+package netconf
+
+import (
+	"context"
+	"encoding/xml"
+)
+
+// MonitoringNamespace is the namespace of the ietf-netconf-monitoring
+// module (RFC 6022).
+const MonitoringNamespace = "urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring"
+
+// NetconfState models the netconf-state container of the
+// ietf-netconf-monitoring module, which describes the server.
+type NetconfState struct {
+	XMLName      xml.Name              `xml:"netconf-state"`
+	Capabilities []string              `xml:"capabilities>capability"`
+	Datastores   []MonitoringDatastore `xml:"datastores>datastore"`
+	Schemas      []MonitoringSchema    `xml:"schemas>schema"`
+	Sessions     []MonitoringSession   `xml:"sessions>session"`
+	Statistics   *MonitoringStatistics `xml:"statistics"`
+}
+
+// MonitoringDatastore describes a datastore, and its global lock, if any.
+type MonitoringDatastore struct {
+	Name       string                `xml:"name"`
+	GlobalLock *MonitoringGlobalLock `xml:"locks>global-lock"`
+}
+
+// MonitoringGlobalLock describes the session holding
+// the global lock of a datastore, and since when.
+type MonitoringGlobalLock struct {
+	LockedBySession uint   `xml:"locked-by-session"`
+	LockedTime      string `xml:"locked-time"`
+}
+
+// MonitoringSchema describes a schema the server can return with get-schema.
+type MonitoringSchema struct {
+	Identifier string   `xml:"identifier"`
+	Version    string   `xml:"version"`
+	Format     string   `xml:"format"`
+	Namespace  string   `xml:"namespace"`
+	Locations  []string `xml:"location"`
+}
+
+// MonitoringSession describes a session established with the server.
+type MonitoringSession struct {
+	SessionID        uint   `xml:"session-id"`
+	Transport        string `xml:"transport"`
+	Username         string `xml:"username"`
+	SourceHost       string `xml:"source-host"`
+	LoginTime        string `xml:"login-time"`
+	InRPCs           uint32 `xml:"in-rpcs"`
+	InBadRPCs        uint32 `xml:"in-bad-rpcs"`
+	OutRPCErrors     uint32 `xml:"out-rpc-errors"`
+	OutNotifications uint32 `xml:"out-notifications"`
+}
+
+// MonitoringStatistics holds the server's counters since it started.
+type MonitoringStatistics struct {
+	NetconfStartTime string `xml:"netconf-start-time"`
+	InBadHellos      uint32 `xml:"in-bad-hellos"`
+	InSessions       uint32 `xml:"in-sessions"`
+	DroppedSessions  uint32 `xml:"dropped-sessions"`
+	InRPCs           uint32 `xml:"in-rpcs"`
+	InBadRPCs        uint32 `xml:"in-bad-rpcs"`
+	OutRPCErrors     uint32 `xml:"out-rpc-errors"`
+	OutNotifications uint32 `xml:"out-notifications"`
+}
+
+// monitoringData models the data of the reply to the netconf-state get.
+type monitoringData struct {
+	XMLName xml.Name     `xml:"data"`
+	State   NetconfState `xml:"netconf-state"`
+}
+
+// MonitoringState gets the netconf-state container of the
+// ietf-netconf-monitoring module, i.e. the server's capabilities,
+// datastores, schemas, sessions and statistics. The containers the server
+// doesn't implement are left empty.
+//
+// If the reply holds errors alongside data, e.g. because the server only
+// implements part of the module, the state decoded from the data is
+// returned with the ReplyError, or MultiError. The session is closed if
+// the context is done before the reply is read.
+func (s *Session) MonitoringState(ctx context.Context) (*NetconfState, error) {
+
+	filter := SubtreeFilterNS(MonitoringNamespace, &struct {
+		XMLName xml.Name `xml:"netconf-state"`
+	}{})
+
+	var data monitoringData
+	err := s.exec(ctx, Get(filter), &data)
+	switch err.(type) {
+	case nil, *ReplyError, *LockDeniedError, *MultiError:
+		return &data.State, err
+	}
+
+	return nil, err
+}
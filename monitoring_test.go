@@ -0,0 +1,176 @@
+package netconf
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSession_MonitoringState(t *testing.T) {
+
+	const reply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<data>
+<netconf-state xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring">
+<capabilities>
+<capability>urn:ietf:params:netconf:base:1.1</capability>
+<capability>urn:ietf:params:netconf:capability:candidate:1.0</capability>
+</capabilities>
+<datastores>
+<datastore>
+<name>running</name>
+<locks>
+<global-lock>
+<locked-by-session>4</locked-by-session>
+<locked-time>2026-10-16T10:00:00Z</locked-time>
+</global-lock>
+</locks>
+</datastore>
+<datastore>
+<name>candidate</name>
+</datastore>
+</datastores>
+<schemas>
+<schema>
+<identifier>ietf-interfaces</identifier>
+<version>2018-02-20</version>
+<format>yang</format>
+<namespace>urn:ietf:params:xml:ns:yang:ietf-interfaces</namespace>
+<location>NETCONF</location>
+</schema>
+</schemas>
+<sessions>
+<session>
+<session-id>4</session-id>
+<transport>netconf-ssh</transport>
+<username>admin</username>
+<source-host>192.0.2.1</source-host>
+<login-time>2026-10-16T09:59:00Z</login-time>
+<in-rpcs>12</in-rpcs>
+<in-bad-rpcs>1</in-bad-rpcs>
+<out-rpc-errors>2</out-rpc-errors>
+<out-notifications>0</out-notifications>
+</session>
+</sessions>
+<statistics>
+<netconf-start-time>2026-10-01T00:00:00Z</netconf-start-time>
+<in-bad-hellos>0</in-bad-hellos>
+<in-sessions>30</in-sessions>
+<dropped-sessions>3</dropped-sessions>
+<in-rpcs>400</in-rpcs>
+<in-bad-rpcs>5</in-bad-rpcs>
+<out-rpc-errors>7</out-rpc-errors>
+<out-notifications>0</out-notifications>
+</statistics>
+</netconf-state>
+</data>
+</rpc-reply>`
+
+	var gotReq []byte
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		gotReq = req
+		return []byte(reply)
+	})
+	defer stop()
+
+	state, err := session.MonitoringState(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &NetconfState{
+		XMLName: state.XMLName,
+		Capabilities: []string{
+			"urn:ietf:params:netconf:base:1.1",
+			"urn:ietf:params:netconf:capability:candidate:1.0",
+		},
+		Datastores: []MonitoringDatastore{
+			{
+				Name: "running",
+				GlobalLock: &MonitoringGlobalLock{
+					LockedBySession: 4,
+					LockedTime:      "2026-10-16T10:00:00Z",
+				},
+			},
+			{Name: "candidate"},
+		},
+		Schemas: []MonitoringSchema{{
+			Identifier: "ietf-interfaces",
+			Version:    "2018-02-20",
+			Format:     "yang",
+			Namespace:  "urn:ietf:params:xml:ns:yang:ietf-interfaces",
+			Locations:  []string{"NETCONF"},
+		}},
+		Sessions: []MonitoringSession{{
+			SessionID:    4,
+			Transport:    "netconf-ssh",
+			Username:     "admin",
+			SourceHost:   "192.0.2.1",
+			LoginTime:    "2026-10-16T09:59:00Z",
+			InRPCs:       12,
+			InBadRPCs:    1,
+			OutRPCErrors: 2,
+		}},
+		Statistics: &MonitoringStatistics{
+			NetconfStartTime: "2026-10-01T00:00:00Z",
+			InSessions:       30,
+			DroppedSessions:  3,
+			InRPCs:           400,
+			InBadRPCs:        5,
+			OutRPCErrors:     7,
+		},
+	}
+
+	if !reflect.DeepEqual(want, state) {
+		t.Errorf("unexpected state\nwant:\t%+v\ngot:\t%+v", want, state)
+	}
+
+	wantFilter := []byte(`<filter type="subtree"><netconf-state xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring"></netconf-state></filter>`)
+	if !bytes.Contains(gotReq, wantFilter) {
+		t.Errorf("request does not contain the filter\nwant:\t%q\ngot:\t%q", wantFilter, gotReq)
+	}
+}
+
+func TestSession_MonitoringState_Partial(t *testing.T) {
+
+	const reply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<rpc-error>
+<error-type>application</error-type>
+<error-tag>operation-not-supported</error-tag>
+<error-severity>error</error-severity>
+<error-message>statistics are not supported</error-message>
+</rpc-error>
+<data>
+<netconf-state xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring">
+<datastores>
+<datastore>
+<name>running</name>
+</datastore>
+</datastores>
+</netconf-state>
+</data>
+</rpc-reply>`
+
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		return []byte(reply)
+	})
+	defer stop()
+
+	state, err := session.MonitoringState(context.Background())
+
+	replyErr, ok := err.(*ReplyError)
+	if !ok {
+		t.Fatalf("unexpected error:\nwant:\t%T\ngot:\t%v", replyErr, err)
+	} else if replyErr.Tag != ErrorTagOpNotSupported {
+		t.Errorf("unexpected error tag\nwant:\t%v\ngot:\t%v", ErrorTagOpNotSupported, replyErr.Tag)
+	}
+
+	want := []MonitoringDatastore{{Name: "running"}}
+	if state == nil {
+		t.Fatal("no partial state returned with the error")
+	} else if !reflect.DeepEqual(want, state.Datastores) {
+		t.Errorf("unexpected datastores\nwant:\t%+v\ngot:\t%+v", want, state.Datastores)
+	} else if state.Statistics != nil {
+		t.Errorf("unexpected statistics: %+v", state.Statistics)
+	}
+}
@@ -0,0 +1,126 @@
+package netconf
+
+import (
+	"encoding/xml"
+)
+
+const (
+	// NMDANamespace is the namespace of the get-data and edit-data
+	// operations, and of the data element in their replies (RFC 8526).
+	NMDANamespace = "urn:ietf:params:xml:ns:yang:ietf-netconf-nmda"
+
+	// DatastoresNamespace is the namespace of the datastore identities
+	// used by get-data and edit-data (RFC 8342).
+	DatastoresNamespace = "urn:ietf:params:xml:ns:yang:ietf-datastores"
+
+	// CapabilityYANGLibrary11 is the capability a server implementing
+	// the NMDA advertises, instead of listing its modules in the hello.
+	CapabilityYANGLibrary11 = "urn:ietf:params:netconf:capability:yang-library:1.1"
+)
+
+const (
+	// NMDARunning is the running configuration datastore.
+	NMDARunning = "ds:running"
+
+	// NMDACandidate is the candidate configuration datastore.
+	NMDACandidate = "ds:candidate"
+
+	// NMDAStartup is the startup configuration datastore.
+	NMDAStartup = "ds:startup"
+
+	// NMDAIntended is the configuration the server intends to apply,
+	// which get-data can read, but edit-data can't write.
+	NMDAIntended = "ds:intended"
+
+	// NMDAOperational is the operational state datastore, holding both
+	// the configuration in use and the device's state, which get-data
+	// can read, but edit-data can't write.
+	NMDAOperational = "ds:operational"
+)
+
+// nmdaDatastore models the datastore parameter of the get-data and
+// edit-data operations, holding an identity qualified by the ds prefix.
+type nmdaDatastore struct {
+	XMLName xml.Name   `xml:"datastore"`
+	Attr    []xml.Attr `xml:",attr"`
+	Name    string     `xml:",chardata"`
+}
+
+// newNMDADatastore returns a datastore parameter declaring the ds prefix.
+func newNMDADatastore(datastore string) nmdaDatastore {
+	return nmdaDatastore{
+		Attr: []xml.Attr{NamespaceAttr("ds", DatastoresNamespace)},
+		Name: datastore,
+	}
+}
+
+// getDataOperation models the get-data operation.
+type getDataOperation struct {
+	XMLName       xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-nmda get-data"`
+	Datastore     nmdaDatastore
+	SubtreeFilter *nmdaSubtreeFilter `xml:",omitempty"`
+	XPathFilter   string             `xml:"xpath-filter,omitempty"`
+}
+
+// nmdaSubtreeFilter models the subtree-filter parameter of get-data.
+type nmdaSubtreeFilter struct {
+	XMLName xml.Name `xml:"subtree-filter"`
+	Subtree interface{}
+}
+
+// editDataOperation models the edit-data operation.
+type editDataOperation struct {
+	XMLName   xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-nmda edit-data"`
+	Datastore nmdaDatastore
+	Config    editConfig
+}
+
+// GetData returns a Method with a get-data operation, which retrieves the
+// data selected by the filter from the given NMDA datastore, e.g.
+// NMDAOperational. A subtree Filter is sent as a subtree-filter, and an
+// XPath Filter as an xpath-filter. The zero Filter selects everything.
+//
+// The data in the reply is in a data element in NMDANamespace, so a struct
+// decoding it should use the xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-nmda data"
+// tag, or no namespace at all. The server must support the NMDA, which can
+// be checked with ValidateNMDA.
+func GetData(datastore string, filter Filter) *Method {
+
+	getData := getDataOperation{
+		Datastore: newNMDADatastore(datastore),
+	}
+
+	switch filter.Type {
+	case FilterTypeSubtree:
+		getData.SubtreeFilter = &nmdaSubtreeFilter{Subtree: filter.Subtree}
+	case FilterTypeXPath:
+		getData.XPathFilter = filter.Select
+	}
+
+	return WrapMethod(&getData)
+}
+
+// EditData returns a Method with an edit-data operation, which merges the
+// given configuration into the given NMDA datastore, e.g. NMDARunning. The
+// config must marshal to one or more XML elements, just like EditConfig.
+// The server must support the NMDA, which can be checked with ValidateNMDA.
+func EditData(datastore string, config interface{}) *Method {
+	return WrapMethod(&editDataOperation{
+		Datastore: newNMDADatastore(datastore),
+		Config:    editConfig{Payload: config},
+	})
+}
+
+// ValidateNMDA returns an UnsupportedCapabilityError if the server with
+// the given hello message does not advertise CapabilityYANGLibrary11,
+// which every server implementing the NMDA advertises. Support for the
+// get-data and edit-data operations themselves is listed in the server's
+// YANG library, as the ietf-netconf-nmda module.
+func ValidateNMDA(serverHello *HelloMessage) error {
+
+	if !serverHello.HasCapability(CapabilityYANGLibrary11) {
+		return &UnsupportedCapabilityError{Capability: CapabilityYANGLibrary11}
+	}
+
+	return nil
+}
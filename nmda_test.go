@@ -0,0 +1,71 @@
+package netconf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestGetData(t *testing.T) {
+
+	type Interfaces struct {
+		XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-interfaces interfaces"`
+	}
+
+	tests := []struct {
+		name   string
+		method *Method
+		want   string
+	}{
+		{
+			"subtree",
+			GetData(NMDAOperational, SubtreeFilter(&Interfaces{})),
+			`<get-data xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-nmda"><datastore xmlns:ds="urn:ietf:params:xml:ns:yang:ietf-datastores">ds:operational</datastore>` +
+				`<subtree-filter><interfaces xmlns="urn:ietf:params:xml:ns:yang:ietf-interfaces"></interfaces></subtree-filter></get-data>`,
+		},
+		{
+			"xpath",
+			GetData(NMDAIntended, XPathFilter("/system")),
+			`<get-data xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-nmda"><datastore xmlns:ds="urn:ietf:params:xml:ns:yang:ietf-datastores">ds:intended</datastore>` +
+				`<xpath-filter>/system</xpath-filter></get-data>`,
+		},
+		{
+			"everything",
+			GetData(NMDARunning, Filter{}),
+			`<get-data xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-nmda"><datastore xmlns:ds="urn:ietf:params:xml:ns:yang:ietf-datastores">ds:running</datastore></get-data>`,
+		},
+		{
+			"edit-data",
+			EditData(NMDARunning, &Interfaces{}),
+			`<edit-data xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-nmda"><datastore xmlns:ds="urn:ietf:params:xml:ns:yang:ietf-datastores">ds:running</datastore>` +
+				`<config><interfaces xmlns="urn:ietf:params:xml:ns:yang:ietf-interfaces"></interfaces></config></edit-data>`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := MarshalMethod(test.method)
+			if err != nil {
+				t.Fatal(err)
+			} else if !bytes.Contains(got, []byte(test.want)) {
+				t.Errorf("unexpected operation\nwant:\t%q\ngot:\t%q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateNMDA(t *testing.T) {
+
+	nmda := &HelloMessage{Capabilities: []string{
+		CapabilityYANGLibrary11 + "?revision=2019-01-04&content-id=1",
+	}}
+	if err := ValidateNMDA(nmda); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := ValidateNMDA(&HelloMessage{}); err == nil {
+		t.Error("expected an error for a server without the NMDA")
+	} else if capErr, ok := err.(*UnsupportedCapabilityError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", capErr, err)
+	}
+}
@@ -0,0 +1,82 @@
+package netconf
+
+import (
+	"encoding/xml"
+)
+
+const (
+	// CapabilityPartialLock is the capability a server advertises when
+	// it supports locking parts of the running datastore (RFC 5717).
+	CapabilityPartialLock = "urn:ietf:params:netconf:capability:partial-lock:1.0"
+
+	// PartialLockNamespace is the namespace of the partial-lock and
+	// partial-unlock operations.
+	PartialLockNamespace = "urn:ietf:params:xml:ns:netconf:partial-lock:1.0"
+)
+
+// partialLock models the partial-lock operation.
+type partialLock struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:netconf:partial-lock:1.0 partial-lock"`
+	Select  []string `xml:"select"`
+}
+
+// partialUnlock models the partial-unlock operation.
+type partialUnlock struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:netconf:partial-lock:1.0 partial-unlock"`
+	LockID  uint     `xml:"lock-id"`
+}
+
+// PartialLock returns a Method that locks the parts of the running
+// datastore selected by the given XPath expressions. The lock-id
+// needed by PartialUnlock is in the reply, which can be decoded
+// into a PartialLockReply.
+//
+// The server must advertise CapabilityPartialLock, which can be
+// checked with ValidatePartialLock.
+func PartialLock(selects []string) *Method {
+	return WrapMethod(&partialLock{Select: selects})
+}
+
+// PartialUnlock returns a Method that releases the partial lock
+// with the given lock-id.
+func PartialUnlock(lockID uint) *Method {
+	return WrapMethod(&partialUnlock{LockID: lockID})
+}
+
+// ValidatePartialLock returns an UnsupportedCapabilityError if the
+// server with the given hello message does not support partial locks.
+func ValidatePartialLock(serverHello *HelloMessage) error {
+
+	if !serverHello.HasCapability(CapabilityPartialLock) {
+		return &UnsupportedCapabilityError{Capability: CapabilityPartialLock}
+	}
+
+	return nil
+}
+
+// PartialLockReply models the data of a reply to a partial-lock
+// operation.
+type PartialLockReply struct {
+	LockID      uint     // LockID identifies the lock for PartialUnlock.
+	LockedNodes []string // LockedNodes are the absolute paths of the locked nodes.
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface. The reply's
+// lock-id and locked-node elements are siblings, so it is called once
+// for each of them when decoding the reply.
+func (r *PartialLockReply) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+
+	switch start.Name.Local {
+	case "lock-id":
+		return d.DecodeElement(&r.LockID, &start)
+	case "locked-node":
+		var node string
+		if err := d.DecodeElement(&node, &start); err != nil {
+			return err
+		}
+		r.LockedNodes = append(r.LockedNodes, node)
+		return nil
+	}
+
+	return d.Skip()
+}
@@ -0,0 +1,78 @@
+package netconf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestPartialLock(t *testing.T) {
+
+	var gotReq []byte
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		gotReq = req
+		return []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">
+<lock-id xmlns="urn:ietf:params:xml:ns:netconf:partial-lock:1.0">127</lock-id>
+<locked-node xmlns="urn:ietf:params:xml:ns:netconf:partial-lock:1.0">/nc:top/nc:users/nc:user[nc:name="fred"]</locked-node>
+<locked-node xmlns="urn:ietf:params:xml:ns:netconf:partial-lock:1.0">/nc:top/nc:users/nc:user[nc:name="barney"]</locked-node>
+</rpc-reply>`)
+	})
+	defer stop()
+
+	method := PartialLock([]string{`/top/users/user[name="fred"]`, `/top/users/user[name="barney"]`})
+	method.Attr[0].Value = "1"
+
+	if err := session.NewEncoder().Encode(method); err != nil {
+		t.Fatal(err)
+	}
+
+	var lockReply PartialLockReply
+	if err := session.NewDecoder().Decode(&lockReply); err != nil {
+		t.Fatal(err)
+	}
+
+	wantReq := []byte(`<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1"><partial-lock xmlns="urn:ietf:params:xml:ns:netconf:partial-lock:1.0"><select>/top/users/user[name=&#34;fred&#34;]</select><select>/top/users/user[name=&#34;barney&#34;]</select></partial-lock></rpc>`)
+	if !bytes.Equal(wantReq, gotReq) {
+		t.Errorf("unexpected request received by server\nwant:\t%q\ngot:\t%q", wantReq, gotReq)
+	}
+
+	want := PartialLockReply{
+		LockID: 127,
+		LockedNodes: []string{
+			`/nc:top/nc:users/nc:user[nc:name="fred"]`,
+			`/nc:top/nc:users/nc:user[nc:name="barney"]`,
+		},
+	}
+	if !reflect.DeepEqual(want, lockReply) {
+		t.Errorf("unexpected partial lock reply\nwant:\t%+v\ngot:\t%+v", want, lockReply)
+	}
+}
+
+func TestPartialUnlock(t *testing.T) {
+
+	b, err := xml.Marshal(PartialUnlock(127).Method[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<partial-unlock xmlns="urn:ietf:params:xml:ns:netconf:partial-lock:1.0"><lock-id>127</lock-id></partial-unlock>`
+	if got := string(b); want != got {
+		t.Errorf("unexpected bytes encoded\nwant:\t%q\ngot:\t%q", want, got)
+	}
+}
+
+func TestValidatePartialLock(t *testing.T) {
+
+	hello := HelloMessage{Capabilities: []string{CapabilityPartialLock}}
+	if err := ValidatePartialLock(&hello); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	hello.Capabilities = nil
+	if err := ValidatePartialLock(&hello); err == nil {
+		t.Error("expected an error without the partial-lock capability")
+	} else if capErr, ok := err.(*UnsupportedCapabilityError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", capErr, err)
+	}
+}
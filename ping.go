@@ -0,0 +1,47 @@
+package netconf
+
+import (
+	"context"
+	"time"
+)
+
+// Ping checks the NETCONF subsystem is responsive, which SSH keepalives
+// alone cannot prove. It sends a get operation with an empty subtree
+// filter, which every server supports and answers without any data,
+// and waits for the reply.
+//
+// Use PingWith to send a different RPC, like a vendor no-op.
+func (s *Session) Ping(ctx context.Context) error {
+	return s.PingWith(ctx, Get(SubtreeFilter(nil)))
+}
+
+// PingWith is like Ping, but it sends the given RPC, which is wrapped
+// like Encode does, and discards the data in its reply.
+//
+// A DeadlineError wrapping context.DeadlineExceeded is returned if the
+// reply does not arrive before the context's deadline. The session is
+// closed when the context is done, since it is no longer usable.
+func (s *Session) PingWith(ctx context.Context, v interface{}) error {
+
+	method, ok := v.(*Method)
+	if !ok {
+		method = WrapMethod(v)
+	}
+
+	begin := time.Now()
+	err := s.exec(ctx, method, &struct{}{})
+	if err == context.DeadlineExceeded {
+		deadlineErr := &DeadlineError{
+			Op:        "ping",
+			BeginTime: begin,
+			FailTime:  time.Now(),
+			Err:       err,
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			deadlineErr.Deadline = deadline.Sub(begin)
+		}
+		return deadlineErr
+	}
+
+	return err
+}
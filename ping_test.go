@@ -0,0 +1,94 @@
+package netconf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSession_Ping(t *testing.T) {
+
+	var gotReq []byte
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		gotReq = req
+		return []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data></data></rpc-reply>`)
+	})
+	defer stop()
+
+	if err := session.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	wantGet := []byte(`<get><filter type="subtree"></filter></get>`)
+	if !bytes.Contains(gotReq, wantGet) {
+		t.Errorf("request does not contain an empty get\nwant:\t%q\ngot:\t%q", wantGet, gotReq)
+	}
+
+	// a second ping proves the first reply was read entirely
+	if err := session.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSession_Ping_Timeout(t *testing.T) {
+
+	// unblock the server before stopping it
+	block := make(chan struct{})
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		<-block
+		return nil
+	})
+	defer stop()
+	defer close(block)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := session.Ping(ctx)
+	if deadlineErr, ok := err.(*DeadlineError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", deadlineErr, err)
+	} else if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DeadlineError does not unwrap to %v: %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestSession_WithReadDeadline(t *testing.T) {
+
+	// unblock the server before stopping it
+	block := make(chan struct{})
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		<-block
+		return nil
+	})
+	defer stop()
+	defer close(block)
+
+	err := session.WithReadDeadline(10 * time.Millisecond).Ping(context.Background())
+	if deadlineErr, ok := err.(*DeadlineError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", deadlineErr, err)
+	} else if deadlineErr.Op != "read" {
+		t.Errorf("unexpected deadline operation\nwant:\t%q\ngot:\t%q", "read", deadlineErr.Op)
+	}
+}
+
+func TestSession_WithReadDeadline_Context(t *testing.T) {
+
+	block := make(chan struct{})
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		<-block
+		return nil
+	})
+	defer stop()
+	defer close(block)
+
+	// the context expires first, so the read deadline is never reached
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := session.WithReadDeadline(time.Minute).Ping(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error\nwant:\t%v\ngot:\t%v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,199 @@
+package netconf
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ReconnectingSession is a Session that transparently redials the target,
+// and exchanges hello messages again, after a transport failure. It is
+// meant for long running automation, where a device reboot or a dropped
+// connection should not require rebuilding the caller's state.
+//
+// RPCs are executed one at a time, so a ReconnectingSession is safe for
+// concurrent use.
+type ReconnectingSession struct {
+	ClientConfig *ssh.ClientConfig
+	Target       string
+	Config       SessionConfig
+
+	// Retries is the number of times an RPC is sent again on a new
+	// session after a transport failure.
+	Retries int
+
+	// Backoff is the delay before redialing after a failure, which is
+	// doubled before every subsequent retry of the same RPC.
+	Backoff time.Duration
+
+	mu      sync.Mutex
+	session *Session
+	hello   *HelloMessage
+
+	// dial establishes a new session, and defaults to NewSessionConfig.
+	dial func(ctx context.Context) (*Session, *HelloMessage, error)
+}
+
+// NewReconnectingSession connects to the target like NewSessionConfig,
+// and returns a ReconnectingSession that retries an RPC once, after a
+// one second backoff.
+func NewReconnectingSession(ctx context.Context, clientConfig *ssh.ClientConfig, target string, config SessionConfig) (*ReconnectingSession, error) {
+
+	r := ReconnectingSession{
+		ClientConfig: clientConfig,
+		Target:       target,
+		Config:       config,
+		Retries:      1,
+		Backoff:      time.Second,
+	}
+
+	if _, err := r.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// Exec sends the given RPC, which is wrapped like Encode does, and decodes
+// its reply into reply, just like Decode.
+//
+// If the RPC fails because of the transport, the dead session is closed,
+// a new one is established, and the RPC is sent again, up to Retries
+// times. Failing to establish the new session counts as a retry, and is
+// followed by the same backoff. Errors reported by the server, like a
+// ReplyError or MultiError, and those raised while decoding its reply,
+// are never retried, since the RPC was already processed. An RPC that
+// fails as the connection drops may have been processed too, so only
+// idempotent RPCs should be sent with Retries set.
+func (r *ReconnectingSession) Exec(ctx context.Context, v interface{}, reply interface{}) error {
+
+	method, ok := v.(*Method)
+	if !ok {
+		method = WrapMethod(v)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	backoff := r.Backoff
+	for retry := 0; ; retry++ {
+
+		// a failed dial, e.g. while the device is still rebooting,
+		// is retried like a failed RPC
+		session, err := r.connect(ctx)
+		if err == nil {
+			err = session.exec(ctx, method, reply)
+			if !isTransportError(err) {
+				return err
+			}
+
+			_ = session.Close()
+			r.session = nil
+		}
+
+		if retry >= r.Retries || ctx.Err() != nil {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// Session returns the current Session, or nil if it failed and the next
+// RPC has not established a new one yet. It must not be used concurrently
+// with Exec, and must not be closed directly.
+func (r *ReconnectingSession) Session() *Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.session
+}
+
+// Hello returns the hello message received when the current session
+// was established.
+func (r *ReconnectingSession) Hello() *HelloMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.hello
+}
+
+// Close closes the current session, if any. A subsequent call to Exec
+// establishes a new one.
+func (r *ReconnectingSession) Close() error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.session == nil {
+		return nil
+	}
+
+	err := r.session.Close()
+	r.session = nil
+
+	return err
+}
+
+// connect returns the current session, establishing a new one if there is
+// none. The caller must hold the lock, except during construction.
+func (r *ReconnectingSession) connect(ctx context.Context) (*Session, error) {
+
+	if r.session != nil {
+		return r.session, nil
+	}
+
+	dial := r.dial
+	if dial == nil {
+		dial = func(ctx context.Context) (*Session, *HelloMessage, error) {
+			return NewSessionConfig(ctx, r.ClientConfig, r.Target, r.Config)
+		}
+	}
+
+	session, hello, err := dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.session, r.hello = session, hello
+
+	return session, nil
+}
+
+// isTransportError reports whether the error was caused by the transport,
+// i.e. the session ended, failed, or missed a deadline, so the RPC may not
+// have reached the server. Errors reported by the server in its reply, and
+// those raised locally, e.g. while decoding the reply's data, are not.
+//
+// A SessionClosedError is a transport error when the read or write it
+// interrupted failed because of the transport.
+func isTransportError(err error) bool {
+
+	if err == nil {
+		return false
+	}
+
+	// the server's errors may be wrapped, e.g. by a SessionClosedError
+	var replyErr *ReplyError
+	var multiErr *MultiError
+	if errors.As(err, &replyErr) || errors.As(err, &multiErr) {
+		return false
+	}
+
+	// net.Error includes the timeouts wrapped in a DeadlineError
+	var netErr net.Error
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.ErrClosedPipe) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.As(err, &netErr)
+}
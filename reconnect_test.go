@@ -0,0 +1,167 @@
+package netconf
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// testDialer returns a dial function for a ReconnectingSession, that
+// connects to test servers replying with the given reply. The first
+// failures sessions are closed before they are returned.
+func testDialer(t *testing.T, reply string, failures int) (func(context.Context) (*Session, *HelloMessage, error), *int) {
+
+	dials := 0
+	return func(context.Context) (*Session, *HelloMessage, error) {
+		session, stop := newTestSession(t, func(req []byte) []byte {
+			return []byte(reply)
+		})
+		t.Cleanup(stop)
+
+		if dials++; dials <= failures {
+			_ = session.Close()
+		}
+		return session, &HelloMessage{SessionID: session.SessionID()}, nil
+	}, &dials
+}
+
+func TestReconnectingSession_Exec(t *testing.T) {
+
+	dial, dials := testDialer(t, `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data></data></rpc-reply>`, 1)
+	r := ReconnectingSession{Retries: 1, dial: dial}
+
+	if err := r.Exec(context.Background(), Get(SubtreeFilter(nil)), &struct{}{}); err != nil {
+		t.Fatal(err)
+	} else if *dials != 2 {
+		t.Errorf("unexpected number of dials\nwant:\t%d\ngot:\t%d", 2, *dials)
+	}
+
+	if r.Session() == nil {
+		t.Error("the new session was not kept")
+	}
+}
+
+func TestReconnectingSession_Exec_Retries(t *testing.T) {
+
+	dial, dials := testDialer(t, "", 2)
+	r := ReconnectingSession{Retries: 1, dial: dial}
+
+	if err := r.Exec(context.Background(), Get(SubtreeFilter(nil)), &struct{}{}); err == nil {
+		t.Fatal("expected an error after the retries are exhausted")
+	} else if *dials != 2 {
+		t.Errorf("unexpected number of dials\nwant:\t%d\ngot:\t%d", 2, *dials)
+	}
+
+	if r.Session() != nil {
+		t.Error("the dead session was kept")
+	}
+}
+
+func TestReconnectingSession_Exec_ReplyError(t *testing.T) {
+
+	dial, dials := testDialer(t, `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<rpc-error>
+<error-type>application</error-type>
+<error-tag>operation-failed</error-tag>
+<error-severity>error</error-severity>
+</rpc-error>
+</rpc-reply>`, 0)
+	r := ReconnectingSession{Retries: 1, dial: dial}
+
+	err := r.Exec(context.Background(), Get(SubtreeFilter(nil)), &struct{}{})
+	if replyErr, ok := err.(*ReplyError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", replyErr, err)
+	} else if *dials != 1 {
+		t.Errorf("a ReplyError was retried\nwant:\t%d dial\ngot:\t%d dials", 1, *dials)
+	}
+}
+
+func TestReconnectingSession_Exec_DecodeError(t *testing.T) {
+
+	dial, dials := testDialer(t, `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data><n>abc</n></data></rpc-reply>`, 0)
+	r := ReconnectingSession{Retries: 1, dial: dial}
+
+	var data struct {
+		XMLName xml.Name `xml:"data"`
+		N       int      `xml:"n"`
+	}
+
+	err := r.Exec(context.Background(), Get(SubtreeFilter(nil)), &data)
+
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		t.Errorf("unexpected error:\nwant:\t%T\ngot:\t%v", numErr, err)
+	} else if *dials != 1 {
+		t.Errorf("an RPC processed by the server was sent again\nwant:\t%d dial\ngot:\t%d dials", 1, *dials)
+	} else if r.Session() == nil {
+		t.Error("the session was closed after a decoding error")
+	}
+}
+
+func TestReconnectingSession_Exec_DialRetries(t *testing.T) {
+
+	dial, _ := testDialer(t, `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data></data></rpc-reply>`, 0)
+
+	// the device refuses the first connection, e.g. while rebooting
+	dials := 0
+	r := ReconnectingSession{Retries: 1, dial: func(ctx context.Context) (*Session, *HelloMessage, error) {
+		if dials++; dials == 1 {
+			return nil, nil, errors.New("connection refused")
+		}
+		return dial(ctx)
+	}}
+
+	if err := r.Exec(context.Background(), Get(SubtreeFilter(nil)), &struct{}{}); err != nil {
+		t.Fatalf("a failed dial was not retried: %v", err)
+	} else if dials != 2 {
+		t.Errorf("unexpected dial count\nwant:\t%d\ngot:\t%d", 2, dials)
+	}
+
+	// without retries left, the dial error is returned
+	dials = 0
+	r = ReconnectingSession{dial: func(ctx context.Context) (*Session, *HelloMessage, error) {
+		dials++
+		return nil, nil, errors.New("connection refused")
+	}}
+
+	if err := r.Exec(context.Background(), Get(SubtreeFilter(nil)), &struct{}{}); err == nil {
+		t.Fatal("expected the dial error, got nil")
+	} else if dials != 1 {
+		t.Errorf("unexpected dial count\nwant:\t%d\ngot:\t%d", 1, dials)
+	}
+}
+
+func TestIsTransportError(t *testing.T) {
+
+	tests := []struct {
+		Err       error
+		Transport bool
+	}{
+		{nil, false},
+		{&ReplyError{Tag: ErrorTagOpFailed}, false},
+		{&SessionClosedError{Err: &ReplyError{Tag: ErrorTagOpFailed}}, false},
+		{fmt.Errorf("exec: %w", &MultiError{}), false},
+		{&SessionClosedError{Err: io.ErrClosedPipe}, true},
+		{io.ErrUnexpectedEOF, true},
+		{&UnexpectedEOFError{Read: 10}, true},
+		{io.EOF, true},
+		{&DeadlineError{Op: "read", Err: os.ErrDeadlineExceeded}, true},
+		{&net.OpError{Op: "read", Net: "tcp", Err: net.ErrClosed}, true},
+		{&strconv.NumError{Func: "ParseInt", Num: "abc", Err: strconv.ErrSyntax}, false},
+		{xml.UnmarshalError("expected element type <data>"), false},
+		{&InvalidMessageIDError{}, false},
+		{&SessionClosedError{Err: &strconv.NumError{Func: "ParseInt", Num: "abc", Err: strconv.ErrSyntax}}, false},
+	}
+
+	for i, test := range tests {
+		if got := isTransportError(test.Err); got != test.Transport {
+			t.Errorf("test %d: unexpected result for %v\nwant:\t%t\ngot:\t%t", i, test.Err, test.Transport, got)
+		}
+	}
+}
@@ -3,22 +3,63 @@ package netconf
 import (
 	"bytes"
 	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
+	"net"
+	"os"
+	"sync"
 	"time"
-	"unicode"
 )
 
-// TODO: add ReplyReader.Reset method
-
 // ReplyReader reads exactly one RPC reply from the session,
 // and discards the message separator. If multiple RPCs need to
-// be read from the session, multiple ReplyReaders will be required.
+// be read from the session, the ReplyReader must be Reset between
+// them, since it may have read the beginning of the next reply.
 // The io.EOF error is returned on every read after the NETCONF message
 // separator is encountered. This is how ReplyReader is able to satisfy
 // the strict interpretation of the io.Reader interface.
 type ReplyReader struct {
+	// MaxMessageSize is the maximum number of bytes read before the
+	// message separator is found. A MessageTooLargeError is returned
+	// when it is exceeded. Zero means there is no limit.
+	MaxMessageSize int64
+
 	session io.Reader // attached to stdout of netconf session
 	err     error     // once an error is generated, always return it on subsequent calls
+	read    int64     // bytes of the current message read so far
+	content bool      // whether anything but whitespace has been read
+	pending []byte    // bytes read from the session, but not yet returned
+	readErr error     // error returned by the session, reported once pending is drained
+	buf     []byte    // buffer WriteTo reads into, kept for reuse
+}
+
+// MessageTooLargeError is returned when a message exceeds the
+// maximum size before a message separator is found.
+type MessageTooLargeError struct {
+	MaxMessageSize int64 // MaxMessageSize is the limit that was exceeded.
+}
+
+// Error is MessageTooLargeError's implementation of the error interface.
+func (e *MessageTooLargeError) Error() string {
+	return fmt.Sprintf("netconf: message exceeds maximum size of %d bytes", e.MaxMessageSize)
+}
+
+// UnexpectedEOFError is returned when the session returns io.EOF
+// after part of a message was read, but before its message separator
+// was found, e.g. when the connection drops mid-reply.
+type UnexpectedEOFError struct {
+	Read int64 // Read is the number of bytes of the message read before io.EOF.
+}
+
+// Error is UnexpectedEOFError's implementation of the error interface.
+func (e *UnexpectedEOFError) Error() string {
+	return fmt.Sprintf("netconf: unexpected EOF after %d bytes, before message separator", e.Read)
+}
+
+// Unwrap returns io.ErrUnexpectedEOF.
+func (e *UnexpectedEOFError) Unwrap() error {
+	return io.ErrUnexpectedEOF
 }
 
 // NewReplyReader assumes the given reader reads from
@@ -36,44 +77,263 @@ func NewReplyReader(session io.Reader) *ReplyReader {
 // Read implements the io.Reader interface by returning io.EOF
 // whenever the standard NETCONF message separator is found in
 // the byte stream.
+//
+// Bytes following the separator in the same read are kept for the next
+// message, which is read after calling Reset. The beginning of a separator
+// split across reads is held back until the rest arrives, so p may be as
+// small as a single byte.
+//
+// If the session returns io.EOF before the message separator is found,
+// an UnexpectedEOFError is returned instead, unless nothing but
+// whitespace was read, in which case the session ended cleanly
+// between messages.
 func (rr *ReplyReader) Read(p []byte) (n int, err error) {
 
 	if rr.err != nil {
 		return 0, rr.err
 	}
 
-	n, rr.err = rr.session.Read(p)
+	// bytes held back because they may begin a separator yield nothing
+	// until the next read tells, so keep reading until something is
+	for n == 0 && rr.err == nil && len(p) != 0 {
+		if len(rr.pending) == 0 && rr.readErr == nil {
+			n = rr.readSession(p)
+		} else {
+			n = rr.readPending(p)
+		}
+	}
 
-	bTrim := bytes.TrimRightFunc(p[:n], unicode.IsSpace)
-	if bytes.HasSuffix(bTrim, messageSeparatorBytes) {
-		n = bytes.LastIndex(bTrim, messageSeparatorBytes)
-		rr.err = io.EOF
+	rr.content = rr.content || len(bytes.TrimSpace(p[:n])) != 0
+	rr.read += int64(n)
+	if rr.MaxMessageSize > 0 && rr.read > rr.MaxMessageSize {
+		n -= int(rr.read - rr.MaxMessageSize)
+		rr.read = rr.MaxMessageSize
+		rr.err = &MessageTooLargeError{MaxMessageSize: rr.MaxMessageSize}
 	}
 
 	return n, rr.err
 }
 
-// Reset clears the internal error field, allowing
-// this reader to be reused.
+// readSession reads from the session directly into p, when nothing is
+// pending. The bytes following a separator, or those that may begin one,
+// are moved to pending.
+func (rr *ReplyReader) readSession(p []byte) int {
+
+	m, err := rr.session.Read(p)
+	rr.readErr = err
+
+	if i := bytes.Index(p[:m], messageSeparatorBytes); i != -1 {
+		// keep the bytes following the separator for the next message
+		rr.pending = append(rr.pending[:0], p[i+len(messageSeparatorBytes):m]...)
+		rr.err = io.EOF
+		return i
+	}
+
+	if err == nil {
+		if k := separatorPrefixLen(p[:m]); k != 0 {
+			rr.pending = append(rr.pending[:0], p[m-k:m]...)
+			return m - k
+		}
+	}
+
+	return m
+}
+
+// readPending returns the pending bytes up to the separator, except those
+// that may begin one, and reads more from the session when nothing else is
+// pending. Once pending is drained, the session's error is reported.
+func (rr *ReplyReader) readPending(p []byte) int {
+
+	if i := bytes.Index(rr.pending, messageSeparatorBytes); i != -1 {
+		n := copy(p, rr.pending[:i])
+		rr.pending = rr.pending[n:]
+		if n == i {
+			rr.pending = rr.pending[len(messageSeparatorBytes):]
+			rr.err = io.EOF
+		}
+		return n
+	}
+
+	// a separator can't begin once the session returned an error
+	ready := len(rr.pending)
+	if rr.readErr == nil {
+		ready -= separatorPrefixLen(rr.pending)
+	}
+
+	if ready != 0 {
+		n := copy(p, rr.pending[:ready])
+		rr.pending = rr.pending[n:]
+		return n
+	}
+
+	if rr.readErr != nil {
+		rr.err = rr.readErr
+		if rr.err == io.EOF && rr.content {
+			rr.err = &UnexpectedEOFError{Read: rr.read}
+		}
+		return 0
+	}
+
+	// only the beginning of a separator is pending, so p is free
+	// to read into, and whatever is read is appended after it
+	m, err := rr.session.Read(p)
+	rr.pending = append(rr.pending, p[:m]...)
+	rr.readErr = err
+
+	return 0
+}
+
+// separatorPrefixLen returns the length of the longest suffix of b
+// that is a proper prefix of the message separator.
+func separatorPrefixLen(b []byte) int {
+	for k := len(messageSeparatorBytes) - 1; k > 0; k-- {
+		if k <= len(b) && bytes.Equal(b[len(b)-k:], messageSeparatorBytes[:k]) {
+			return k
+		}
+	}
+	return 0
+}
+
+// writeToBufSize is the size of the buffer WriteTo reads into. It is
+// larger than the buffer used by io.Copy, so large replies are copied
+// with fewer reads.
+const writeToBufSize = 64 << 10
+
+// WriteTo implements the io.WriterTo interface, so io.Copy writes the
+// reply to w without allocating its own buffer. The message separator
+// is stripped exactly like Read, and reaching it is not an error.
+func (rr *ReplyReader) WriteTo(w io.Writer) (written int64, err error) {
+
+	if rr.buf == nil {
+		rr.buf = make([]byte, writeToBufSize)
+	}
+	buf := rr.buf
+
+	for {
+		n, readErr := rr.Read(buf)
+		if n > 0 {
+			wn, writeErr := w.Write(buf[:n])
+			written += int64(wn)
+			if writeErr != nil {
+				return written, writeErr
+			} else if wn != n {
+				return written, io.ErrShortWrite
+			}
+		}
+		if readErr == io.EOF {
+			return written, nil
+		} else if readErr != nil {
+			return written, readErr
+		}
+	}
+}
+
+// Reset clears the internal error field and byte count,
+// allowing this reader to be reused for the next message.
+// Bytes of the next message already read are kept.
 func (rr *ReplyReader) Reset() {
 	rr.err = nil
+	rr.readErr = nil
+	rr.read = 0
+	rr.content = false
+}
+
+// replyReaderPool holds ReplyReaders returned by PutReplyReader.
+var replyReaderPool = sync.Pool{
+	New: func() interface{} {
+		return new(ReplyReader)
+	},
+}
+
+// GetReplyReader is like NewReplyReader, but the ReplyReader is taken
+// from a pool, along with the buffers it allocated while in use before,
+// which saves allocations for pollers reading many short-lived replies.
+// It should be returned with PutReplyReader once the reply is read.
+func GetReplyReader(session io.Reader) *ReplyReader {
+	rr := replyReaderPool.Get().(*ReplyReader)
+	rr.session = session
+	return rr
+}
+
+// PendingReplyError is returned by PutReplyReader when the ReplyReader
+// already read the beginning of the next reply from the session, which
+// would be lost if the ReplyReader was returned to the pool.
+type PendingReplyError struct {
+	Pending int // Pending is the number of bytes of the next reply already read.
+}
+
+// Error is PendingReplyError's implementation of the error interface.
+func (e *PendingReplyError) Error() string {
+	return fmt.Sprintf("netconf: %d bytes of the next reply already read, reset the ReplyReader to read them", e.Pending)
+}
+
+// PutReplyReader returns a ReplyReader obtained with GetReplyReader to
+// the pool, and it must not be used afterwards. Its state is cleared
+// entirely, including MaxMessageSize.
+//
+// If it already read the beginning of the next reply, i.e. anything but
+// whitespace following the separator, the ReplyReader is not returned to
+// the pool, and a PendingReplyError is returned instead, so the next reply
+// isn't silently corrupted. The ReplyReader is left untouched, so it can
+// be Reset to read the next reply.
+func PutReplyReader(rr *ReplyReader) error {
+
+	if pending := bytes.TrimLeft(rr.pending, " \t\r\n"); len(pending) != 0 {
+		return &PendingReplyError{Pending: len(pending)}
+	}
+
+	*rr = ReplyReader{
+		pending: rr.pending[:0],
+		buf:     rr.buf,
+	}
+	replyReaderPool.Put(rr)
+
+	return nil
 }
 
 // WithDeadline decorates the ReplyReader with a DeadlineReader.
 // The DeadlineReader sets its deadline before every call to Read.
+//
+// The ReplyReader's underlying reader must also be an io.Closer (e.g. the
+// Session), which is closed when the deadline expires to interrupt the
+// pending read, otherwise every Read returns ErrNotInterruptible.
 func (rr *ReplyReader) WithDeadline(deadline time.Duration) *DeadlineReader {
+	closer, _ := rr.session.(io.Closer)
 	return &DeadlineReader{
 		reader:   rr,
+		closer:   closer,
 		deadline: deadline,
 	}
 }
 
+// ErrNotInterruptible is returned by a DeadlineReader whose underlying
+// reader is not an io.Closer, because a read that misses the deadline
+// could not be interrupted, and would be left running in the background.
+var ErrNotInterruptible = errors.New("netconf: deadline set on a reader that can't be closed")
+
 // DeadlineReader is a decorator for an io.Reader that sets a deadline
 // before every read. It can only be constructed by a ReplyReader's
 // WithDeadline method.
+//
+// A pending read can only be interrupted by closing the resource it is
+// reading from, so a closer (e.g. the Session) is required, and Read
+// returns ErrNotInterruptible without one. The closer is closed upon
+// expiry of the deadline, and the read is waited upon for at most another
+// deadline before returning. Reads go through an internal buffer, so a
+// read that closing did not interrupt can't write into the caller's buffer
+// after Read returns, and every later Read returns the same DeadlineError.
 type DeadlineReader struct {
 	reader   io.Reader     // NETCONF session's stdout reader
+	closer   io.Closer     // closed when the deadline expires to interrupt the pending read
 	deadline time.Duration // deadline to set before every call to Read
+	buf      []byte        // buffer the underlying reader reads into
+	err      error         // DeadlineError of a read abandoned after closing
+}
+
+// readResult carries the return values of a Read across a channel.
+type readResult struct {
+	n   int
+	err error
 }
 
 // Read sets a deadline before every call to Read, and returns a DeadlineError
@@ -83,27 +343,64 @@ type DeadlineReader struct {
 // its stdout stream after the deadline expired.
 func (dr *DeadlineReader) Read(b []byte) (n int, err error) {
 
-	var begin time.Time
+	if dr.err != nil {
+		return 0, dr.err
+	} else if dr.closer == nil {
+		return 0, ErrNotInterruptible
+	}
+
+	if cap(dr.buf) < len(b) {
+		dr.buf = make([]byte, len(b))
+	}
+	buf := dr.buf[:len(b)]
+
+	begin := time.Now()
 	timer := time.NewTimer(dr.deadline)
 	defer timer.Stop()
 
-	ch := make(chan struct{})
+	// buffered so an abandoned read never blocks on send
+	ch := make(chan readResult, 1)
 	go func() {
-		begin = time.Now()
-		n, err = dr.reader.Read(b)
-		ch <- struct{}{}
+		n, err := dr.reader.Read(buf)
+		ch <- readResult{n: n, err: err}
 	}()
 
 	select {
-	case <-ch:
-		return n, err
+	case res := <-ch:
+		n = copy(b, buf[:res.n])
+		if netErr, ok := res.err.(net.Error); ok && netErr.Timeout() {
+			return n, &DeadlineError{
+				Op:        "read",
+				BeginTime: begin,
+				FailTime:  time.Now(),
+				Deadline:  dr.deadline,
+				Err:       res.err,
+			}
+		}
+		return n, res.err
 	case timeDone := <-timer.C:
-		return n, &DeadlineError{
+		deadlineErr := &DeadlineError{
 			Op:        "read",
 			BeginTime: begin,
 			FailTime:  timeDone,
 			Deadline:  dr.deadline,
+			Err:       os.ErrDeadlineExceeded,
 		}
+
+		_ = dr.closer.Close()
+
+		closeTimer := time.NewTimer(dr.deadline)
+		defer closeTimer.Stop()
+
+		select {
+		case <-ch:
+		case <-closeTimer.C:
+			// the read still owns the buffer
+			dr.buf = nil
+			dr.err = deadlineErr
+		}
+
+		return 0, deadlineErr
 	}
 }
 
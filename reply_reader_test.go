@@ -2,9 +2,14 @@ package netconf
 
 import (
 	"bytes"
+	"errors"
 	"io"
+	"os"
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 	"unicode"
 )
 
@@ -31,6 +36,395 @@ func TestTrimReader_Read(t *testing.T) {
 	}
 }
 
+func TestReplyReader_WriteTo(t *testing.T) {
+
+	// hide WriteTo, so io.Copy uses the generic Read loop
+	var want bytes.Buffer
+	if _, err := io.Copy(&want, struct{ io.Reader }{NewReplyReader(strings.NewReader(SRX240NewlineRPC))}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got bytes.Buffer
+	written, err := NewReplyReader(strings.NewReader(SRX240NewlineRPC)).WriteTo(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(want.Bytes(), got.Bytes()) {
+		t.Errorf("unexpected bytes written:\nwant:\t%q\ngot:\t%q", want.Bytes(), got.Bytes())
+	} else if written != int64(want.Len()) {
+		t.Errorf("unexpected byte count written:\nwant:\t%d\ngot:\t%d", want.Len(), written)
+	}
+}
+
+// chunkReader returns one chunk on every Read.
+type chunkReader struct {
+	chunks []string
+}
+
+func (cr *chunkReader) Read(p []byte) (int, error) {
+	if len(cr.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, cr.chunks[0])
+	cr.chunks = cr.chunks[1:]
+	return n, nil
+}
+
+func TestReplyReader_Read_Pipelined(t *testing.T) {
+
+	tests := [][]string{
+		// two messages in a single read
+		{"<rpc-reply><ok/></rpc-reply>]]>]]>\n<rpc-reply><data/></rpc-reply>]]>]]>\n"},
+		// the separator split across reads
+		{"<rpc-reply><ok/></rpc-reply>]]>", "]]>\n<rpc-reply><data/></rpc-reply>]", "]>]]>\n"},
+		// a separator prefix that is not a separator
+		{"<rpc-reply><ok/></rpc-reply>]]>]", "]]>\n<rpc-reply><data/></rpc-reply>]]>]]>"},
+	}
+
+	for i, chunks := range tests {
+
+		replyReader := NewReplyReader(&chunkReader{chunks: chunks})
+
+		var got []string
+		for j := 0; j < 2; j++ {
+			b, err := io.ReadAll(replyReader)
+			if err != nil {
+				t.Fatalf("test %d: %v", i, err)
+			}
+			got = append(got, strings.TrimSpace(string(b)))
+			replyReader.Reset()
+		}
+
+		want := []string{"<rpc-reply><ok/></rpc-reply>", "<rpc-reply><data/></rpc-reply>"}
+		if i == 2 {
+			want[0] = "<rpc-reply><ok/></rpc-reply>]]>]]]>\n<rpc-reply><data/></rpc-reply>"
+			want = want[:1]
+			got = got[:1]
+		}
+
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("test %d: unexpected messages read\nwant:\t%q\ngot:\t%q", i, want, got)
+		}
+	}
+}
+
+func TestReplyReader_Read_SmallBuffer(t *testing.T) {
+
+	tests := []struct {
+		Input string
+		Want  []string
+	}{
+		{
+			// the second message begins with a separator prefix, and is
+			// pending entirely once the first one is read in one go
+			Input: "<a/>]]>]]>abc]]x<b/>]]>]]>",
+			Want:  []string{"<a/>", "abc]]x<b/>"},
+		},
+		{
+			Input: "<a>x]y</a>]]>]]>",
+			Want:  []string{"<a>x]y</a>"},
+		},
+		{
+			Input: "<a>]]]]>]</a>]]>]]>\n<b>]]>]</b>]]>]]>",
+			Want:  []string{"<a>]]]]>]</a>", "\n<b>]]>]</b>"},
+		},
+	}
+
+	for i, test := range tests {
+		for _, firstSize := range []int{1, 512} {
+			for size := 1; size <= 8; size++ {
+
+				replyReader := NewReplyReader(strings.NewReader(test.Input))
+
+				var got []string
+				for j, bufSize := 0, firstSize; j < len(test.Want); j, bufSize = j+1, size {
+
+					var msg []byte
+					buf := make([]byte, bufSize)
+					for {
+						n, err := replyReader.Read(buf)
+						msg = append(msg, buf[:n]...)
+						if err == io.EOF {
+							break
+						} else if err != nil {
+							t.Fatalf("test %d, sizes %d/%d: %v", i, firstSize, size, err)
+						}
+					}
+
+					got = append(got, string(msg))
+					replyReader.Reset()
+				}
+
+				if !reflect.DeepEqual(test.Want, got) {
+					t.Errorf("test %d, sizes %d/%d: unexpected messages read\nwant:\t%q\ngot:\t%q", i, firstSize, size, test.Want, got)
+				}
+			}
+		}
+	}
+}
+
+func TestPutReplyReader(t *testing.T) {
+
+	replyReader := GetReplyReader(strings.NewReader("<rpc-reply><ok/></rpc-reply>]]>]]>\n"))
+	replyReader.MaxMessageSize = 64
+	if _, err := io.Copy(io.Discard, replyReader); err != nil {
+		t.Fatal(err)
+	}
+	if err := PutReplyReader(replyReader); err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing of the previous use may leak into the next one, even when
+	// the pool hands back the same ReplyReader
+	replyReader = GetReplyReader(strings.NewReader(SRX240NewlineRPC))
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, replyReader); err != nil {
+		t.Fatalf("unexpected error from a reused ReplyReader: %v", err)
+	} else if err := PutReplyReader(replyReader); err != nil {
+		t.Fatal(err)
+	}
+
+	want := bytes.TrimSuffix(bytes.TrimRightFunc([]byte(SRX240NewlineRPC), unicode.IsSpace), messageSeparatorBytes)
+	if !bytes.Equal(want, buf.Bytes()) {
+		t.Errorf("unexpected reader output:\nwant:\t%q\ngot:\t%q", want, buf.Bytes())
+	}
+}
+
+func TestPutReplyReader_Pending(t *testing.T) {
+
+	const next = "<rpc-reply><ok/></rpc-reply>]]>]]>\n"
+
+	// both replies arrive in a single read
+	replyReader := GetReplyReader(strings.NewReader("<rpc-reply><ok/></rpc-reply>]]>]]>\n" + next))
+	if _, err := io.Copy(io.Discard, replyReader); err != nil {
+		t.Fatal(err)
+	}
+
+	var pendingErr *PendingReplyError
+	if err := PutReplyReader(replyReader); !errors.As(err, &pendingErr) {
+		t.Fatalf("unexpected error:\nwant:\t%T\ngot:\t%v", pendingErr, err)
+	}
+
+	// the ReplyReader was kept intact, so the next reply is still readable
+	replyReader.Reset()
+	got, err := io.ReadAll(replyReader)
+	if err != nil {
+		t.Fatal(err)
+	} else if want := "<rpc-reply><ok/></rpc-reply>"; want != string(bytes.TrimSpace(got)) {
+		t.Errorf("unexpected next reply:\nwant:\t%q\ngot:\t%q", want, got)
+	} else if err := PutReplyReader(replyReader); err != nil {
+		t.Errorf("unexpected error once the next reply was read: %v", err)
+	}
+}
+
+func BenchmarkNewReplyReader(b *testing.B) {
+
+	b.ReportAllocs()
+	r := strings.NewReader(SRX240NewlineRPC)
+	for i := 0; i < b.N; i++ {
+		r.Reset(SRX240NewlineRPC)
+		if _, err := io.Copy(io.Discard, NewReplyReader(r)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetReplyReader(b *testing.B) {
+
+	b.ReportAllocs()
+	r := strings.NewReader(SRX240NewlineRPC)
+	for i := 0; i < b.N; i++ {
+		r.Reset(SRX240NewlineRPC)
+		replyReader := GetReplyReader(r)
+		if _, err := io.Copy(io.Discard, replyReader); err != nil {
+			b.Fatal(err)
+		}
+		if err := PutReplyReader(replyReader); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestReplyReader_Read_MaxMessageSize(t *testing.T) {
+
+	ncReader := NewReplyReader(strings.NewReader(SRX240NewlineRPC))
+	ncReader.MaxMessageSize = 64
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, ncReader); err == nil {
+		t.Error("expected a MessageTooLargeError, got nil")
+	} else if tooLargeErr, ok := err.(*MessageTooLargeError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", tooLargeErr, err)
+	} else if buf.Len() != 64 {
+		t.Errorf("unexpected byte count read:\nwant:\t%d\ngot:\t%d", 64, buf.Len())
+	}
+
+	ncReader = NewReplyReader(strings.NewReader(SRX240NewlineRPC))
+	ncReader.MaxMessageSize = int64(len(SRX240NewlineRPC))
+
+	buf.Reset()
+	if _, err := io.Copy(&buf, ncReader); err != nil {
+		t.Errorf("unexpected error with a sufficient MaxMessageSize: %v", err)
+	}
+}
+
+func TestReplyReader_Read_UnexpectedEOF(t *testing.T) {
+
+	const truncated = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<software-information>`
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, NewReplyReader(strings.NewReader(truncated))); err == nil {
+		t.Error("expected an UnexpectedEOFError, got nil")
+	} else if eofErr, ok := err.(*UnexpectedEOFError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", eofErr, err)
+	} else if eofErr.Read != int64(len(truncated)) {
+		t.Errorf("unexpected byte count read:\nwant:\t%d\ngot:\t%d", len(truncated), eofErr.Read)
+	} else if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("UnexpectedEOFError does not unwrap to %v: %v", io.ErrUnexpectedEOF, err)
+	}
+
+	// only whitespace before io.EOF is a clean end of the session
+	buf.Reset()
+	if _, err := io.Copy(&buf, NewReplyReader(strings.NewReader("\n"))); err != nil {
+		t.Errorf("unexpected error at a clean end of session: %v", err)
+	}
+}
+
+// blockingReadCloser blocks every Read until it is closed.
+type blockingReadCloser struct {
+	closed chan struct{}
+}
+
+func (brc *blockingReadCloser) Read(p []byte) (int, error) {
+	<-brc.closed
+	return 0, io.ErrClosedPipe
+}
+
+func (brc *blockingReadCloser) Close() error {
+	close(brc.closed)
+	return nil
+}
+
+func TestDeadlineReader_Read_NoLeak(t *testing.T) {
+
+	before := runtime.NumGoroutine()
+
+	brc := &blockingReadCloser{closed: make(chan struct{})}
+	dr := NewReplyReader(brc).WithDeadline(10 * time.Millisecond)
+
+	if _, err := dr.Read(make([]byte, 8)); err == nil {
+		t.Fatal("expected a DeadlineError, got nil")
+	} else if _, ok := err.(*DeadlineError); !ok {
+		t.Fatalf("unexpected error type:\nwant:\t%T\ngot:\t%T", &DeadlineError{}, err)
+	} else if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("DeadlineError does not unwrap to %v: %v", os.ErrDeadlineExceeded, err)
+	}
+
+	// give the runtime a moment to reap the exited goroutine
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(time.Millisecond)
+	}
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("goroutine leaked after deadline:\nwant:\t%d\ngot:\t%d", before, after)
+	}
+}
+
+// timeoutReader returns the given error on every Read.
+type timeoutReader struct {
+	err error
+}
+
+func (tr timeoutReader) Read(p []byte) (int, error) {
+	return 0, tr.err
+}
+
+func (tr timeoutReader) Close() error {
+	return nil
+}
+
+// stuckReadCloser blocks every Read until released, even once closed.
+type stuckReadCloser struct {
+	released chan struct{}
+}
+
+func (src *stuckReadCloser) Read(p []byte) (int, error) {
+	<-src.released
+	return copy(p, "late"), nil
+}
+
+func (src *stuckReadCloser) Close() error {
+	return nil
+}
+
+func TestDeadlineReader_Read_NotInterrupted(t *testing.T) {
+
+	src := &stuckReadCloser{released: make(chan struct{})}
+	dr := NewReplyReader(src).WithDeadline(10 * time.Millisecond)
+
+	b := make([]byte, 8)
+	ch := make(chan error, 1)
+	go func() {
+		_, err := dr.Read(b)
+		ch <- err
+	}()
+
+	select {
+	case err := <-ch:
+		var deadlineErr *DeadlineError
+		if !errors.As(err, &deadlineErr) {
+			t.Fatalf("unexpected error type:\nwant:\t%T\ngot:\t%T", deadlineErr, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Read blocked on a read closing did not interrupt")
+	}
+
+	// the abandoned read completes into the internal buffer,
+	// which the race detector checks is not the caller's
+	close(src.released)
+	time.Sleep(10 * time.Millisecond)
+	if !bytes.Equal(b, make([]byte, 8)) {
+		t.Errorf("abandoned read wrote into the caller's buffer: %q", b)
+	}
+
+	if _, err := dr.Read(b); err == nil {
+		t.Error("expected the DeadlineError again after an abandoned read, got nil")
+	}
+}
+
+func TestDeadlineReader_Read_NotInterruptible(t *testing.T) {
+
+	// a plain io.Reader can't be closed to interrupt a read
+	dr := NewReplyReader(struct{ io.Reader }{strings.NewReader(SRX240NewlineRPC)}).WithDeadline(time.Second)
+	if _, err := dr.Read(make([]byte, 8)); err != ErrNotInterruptible {
+		t.Errorf("unexpected error:\nwant:\t%v\ngot:\t%v", ErrNotInterruptible, err)
+	}
+}
+
+func TestDeadlineReader_Read_WrapsTimeout(t *testing.T) {
+
+	dr := NewReplyReader(timeoutReader{err: os.ErrDeadlineExceeded}).WithDeadline(time.Second)
+
+	_, err := dr.Read(make([]byte, 8))
+
+	var deadlineErr *DeadlineError
+	if !errors.As(err, &deadlineErr) {
+		t.Fatalf("unexpected error type:\nwant:\t%T\ngot:\t%T", deadlineErr, err)
+	} else if deadlineErr.Op != "read" {
+		t.Errorf("unexpected op:\nwant:\t%q\ngot:\t%q", "read", deadlineErr.Op)
+	} else if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("original error is not reachable via Unwrap: %v", err)
+	}
+
+	dr = NewReplyReader(timeoutReader{err: io.ErrUnexpectedEOF}).WithDeadline(time.Second)
+	if _, err := dr.Read(make([]byte, 8)); err != io.ErrUnexpectedEOF {
+		t.Errorf("unexpected error:\nwant:\t%v\ngot:\t%v", io.ErrUnexpectedEOF, err)
+	}
+}
+
 const SRX240NewlineRPC = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/15.1X49/junos">
 <interface-information xmlns="http://xml.juniper.net/junos/15.1X49/junos-interface" junos:style="normal">
 <physical-interface>
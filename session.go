@@ -1,9 +1,12 @@
 package netconf
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
-	"strings"
+	"net"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -18,6 +21,48 @@ type Session struct {
 	writeCloser io.WriteCloser
 	sshSession  *ssh.Session
 	sshClient   *ssh.Client
+	rawHello    []byte
+	hello       *HelloMessage // server's hello, for capability checks
+	sessionID   uint
+	writeErr    error // poisons every Encoder after a failed write
+
+	readDeadline time.Duration    // deadline of every read by the session's decoders
+	counters     *sessionCounters // traffic counters returned by Stats
+	tracer       *tracer          // copies the traffic to the writer given to SetTrace
+	closed       int32            // set atomically by Close, so pending RPCs return a SessionClosedError
+}
+
+// SessionClosedError is returned by an RPC of the session, like Ping
+// or ExecReply, that was pending, or started, after Close was called.
+type SessionClosedError struct {
+	Err error // Err is the error returned by the interrupted read or write.
+}
+
+// Error is SessionClosedError's implementation of the error interface.
+func (e *SessionClosedError) Error() string {
+	return fmt.Sprintf("netconf: session closed: %v", e.Err)
+}
+
+// Unwrap returns the error returned by the interrupted read or write.
+func (e *SessionClosedError) Unwrap() error {
+	return e.Err
+}
+
+// closedError wraps the error in a SessionClosedError,
+// if it is not nil, and Close was called.
+func (s *Session) closedError(err error) error {
+	if err != nil && atomic.LoadInt32(&s.closed) != 0 {
+		return &SessionClosedError{Err: err}
+	}
+	return err
+}
+
+// closerFunc is an io.Closer calling the function.
+type closerFunc func() error
+
+// Close calls the function.
+func (f closerFunc) Close() error {
+	return f()
 }
 
 // NewSession creates a new session ready for use with the NETCONF SSH subsystem.
@@ -26,52 +71,274 @@ type Session struct {
 // Hello messages are negotiated, and the server's hello message is returned along
 // with a newly allocated Session pointer.
 func NewSession(clientConfig *ssh.ClientConfig, target string) (*Session, *HelloMessage, error) {
+	return NewSessionContext(context.Background(), clientConfig, target)
+}
+
+// NewSessionContext is like NewSession, but connecting, the SSH handshake,
+// the netconf subsystem request, and the hello exchange are aborted if the
+// given context is cancelled before the session is established. This keeps
+// a device that accepts the SSH channel, but never sends a hello, from
+// blocking forever.
+func NewSessionContext(ctx context.Context, clientConfig *ssh.ClientConfig, target string) (*Session, *HelloMessage, error) {
+	return NewSessionConfig(ctx, clientConfig, target, SessionConfig{})
+}
+
+// DefaultSubsystem is the SSH subsystem NETCONF is requested from.
+const DefaultSubsystem = "netconf"
+
+// SessionConfig configures how NETCONF is started on the SSH connection.
+// Its zero value requests DefaultSubsystem.
+type SessionConfig struct {
+	// Subsystem is the SSH subsystem to request, for devices that expose
+	// NETCONF under a name other than DefaultSubsystem.
+	Subsystem string
+
+	// Command, when set, is run instead of requesting a subsystem, for
+	// older devices that start NETCONF with a shell command.
+	Command string
+
+	// HelloTimeout, when set, bounds the hello exchange on its own, so a
+	// device that accepts the channel, but never sends its hello, fails
+	// with a HelloTimeoutError, independently of the context's deadline
+	// and of the read deadline set with WithReadDeadline.
+	HelloTimeout time.Duration
+
+	// ClientCapabilities, when set, replace the capabilities of
+	// DefaultHelloMessage in the hello sent to the server, so they must
+	// include a base capability, e.g. base:1.1 and :interleave, or
+	// base:1.0 only. A MissingBaseCapabilityError is returned otherwise,
+	// before the hello exchange.
+	ClientCapabilities []string
+
+	// Trace, when set, is the io.Writer the session's traffic is copied
+	// to from the start, including the hello messages, like SetTrace.
+	Trace io.Writer
+}
+
+// HelloTimeoutError is returned when the hello exchange does not
+// complete within SessionConfig.HelloTimeout.
+type HelloTimeoutError struct {
+	Timeout time.Duration // Timeout is the HelloTimeout that expired.
+}
+
+// Error is HelloTimeoutError's implementation of the error interface.
+func (e *HelloTimeoutError) Error() string {
+	return fmt.Sprintf("netconf: hello exchange timed out after %s", e.Timeout)
+}
+
+// SubsystemError is returned when NETCONF could not be started,
+// because the device rejected the subsystem request or command.
+type SubsystemError struct {
+	Subsystem string // Subsystem is the requested subsystem, if Command was empty.
+	Command   string // Command is the command that was run instead, if any.
+	Err       error  // Err is the error returned by the SSH session.
+}
+
+// Error is SubsystemError's implementation of the error interface.
+func (e *SubsystemError) Error() string {
+	if e.Command != "" {
+		return fmt.Sprintf("netconf: command %q failed: %v", e.Command, e.Err)
+	}
+	return fmt.Sprintf("netconf: request for subsystem %q failed: %v", e.Subsystem, e.Err)
+}
+
+// Unwrap returns the error returned by the SSH session.
+func (e *SubsystemError) Unwrap() error {
+	return e.Err
+}
+
+// NewSessionConfig is like NewSessionContext, but NETCONF is started
+// as described by the given SessionConfig.
+func NewSessionConfig(ctx context.Context, clientConfig *ssh.ClientConfig, target string, config SessionConfig) (*Session, *HelloMessage, error) {
 
 	var session Session
 	var err error
 
-	session.sshClient, err = ssh.Dial("tcp", target, clientConfig)
-	if err != nil {
+	if session.sshClient, err = dialContext(ctx, clientConfig, target); err != nil {
 		return nil, nil, err
 	}
 
-	if session.sshSession, err = session.sshClient.NewSession(); err != nil {
-		_ = session.sshClient.Close()
+	setupDone := make(chan struct{})
+	watchDone := make(chan struct{})
+
+	// closing the client unblocks any pending request or read
+	go func() {
+		defer close(watchDone)
+		select {
+		case <-ctx.Done():
+			_ = session.sshClient.Close()
+		case <-setupDone:
+		}
+	}()
+
+	helloMessage, err := session.setup(config)
+	close(setupDone)
+	<-watchDone
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		_ = session.Close()
+		return nil, nil, ctxErr
+	} else if err != nil {
+		_ = session.Close()
 		return nil, nil, err
 	}
 
-	closeAll := func() {
-		_ = session.sshClient.Close()
-		_ = session.sshSession.Close()
+	return &session, helloMessage, nil
+}
+
+// setup starts NETCONF on a new SSH session of the connected
+// client, as described by the config, and exchanges hello messages.
+func (s *Session) setup(config SessionConfig) (*HelloMessage, error) {
+
+	var err error
+
+	if s.sshSession, err = s.sshClient.NewSession(); err != nil {
+		return nil, err
 	}
 
-	if err := session.sshSession.RequestSubsystem("netconf"); err != nil {
-		closeAll()
-		return nil, nil, err
+	// the pipes must be requested before the command is started
+	stdout, err := s.sshSession.StdoutPipe()
+	if err != nil {
+		return nil, err
 	}
 
-	if session.reader, err = session.sshSession.StdoutPipe(); err != nil {
-		closeAll()
-		return nil, nil, err
+	stdin, err := s.sshSession.StdinPipe()
+	if err != nil {
+		return nil, err
 	}
 
-	if session.writeCloser, err = session.sshSession.StdinPipe(); err != nil {
-		closeAll()
-		return nil, nil, err
+	s.attach(stdout, stdin, config.Trace)
+
+	if config.Command != "" {
+		if err = s.sshSession.Start(config.Command); err != nil {
+			return nil, &SubsystemError{Command: config.Command, Err: err}
+		}
+	} else {
+		subsystem := config.Subsystem
+		if subsystem == "" {
+			subsystem = DefaultSubsystem
+		}
+		if err = s.sshSession.RequestSubsystem(subsystem); err != nil {
+			return nil, &SubsystemError{Subsystem: subsystem, Err: err}
+		}
 	}
 
+	return s.exchangeHelloTimeout(config.HelloTimeout, config.ClientCapabilities)
+}
+
+// exchangeHelloTimeout is like exchangeHello, but the session is closed,
+// to interrupt the pending read or write, if the exchange does not
+// complete within the timeout. A zero timeout waits forever.
+func (s *Session) exchangeHelloTimeout(timeout time.Duration, capabilities []string) (*HelloMessage, error) {
+
+	if timeout <= 0 {
+		return s.exchangeHello(capabilities)
+	}
+
+	timedOut := make(chan struct{})
+	timer := time.AfterFunc(timeout, func() {
+		defer close(timedOut)
+		_ = s.shutdown()
+	})
+
+	helloMessage, err := s.exchangeHello(capabilities)
+	if !timer.Stop() {
+		// the session was closed, so whatever exchangeHello
+		// returned is a consequence of the timeout
+		<-timedOut
+		return nil, &HelloTimeoutError{Timeout: timeout}
+	}
+
+	return helloMessage, err
+}
+
+// exchangeHello decodes the server's hello message, keeping a copy
+// of its raw bytes and session-id, and then sends a hello advertising
+// the given capabilities, or DefaultHelloMessage if there are none.
+func (s *Session) exchangeHello(capabilities []string) (*HelloMessage, error) {
+
+	// the client hello is built first, so invalid
+	// capabilities fail without reading from the server
+	clientHello, err := clientHelloMessage(capabilities)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw bytes.Buffer
 	var helloMessage HelloMessage
-	if err := session.NewDecoder().DecodeHello(&helloMessage); err != nil {
-		closeAll()
-		return nil, nil, err
+	if err := NewDecoder(io.TeeReader(s.reader, &raw)).DecodeHello(&helloMessage); err != nil {
+		return nil, err
 	}
 
-	if _, err := io.Copy(&session, strings.NewReader(DefaultHelloMessage)); err != nil {
-		closeAll()
-		return nil, nil, err
+	if i := bytes.Index(raw.Bytes(), messageSeparatorBytes); i != -1 {
+		raw.Truncate(i)
+	}
+	s.rawHello = bytes.TrimSpace(raw.Bytes())
+	s.hello = helloMessage.Copy()
+	s.sessionID = helloMessage.SessionID
+
+	if _, err := io.Copy(s, bytes.NewReader(clientHello)); err != nil {
+		return nil, err
+	}
+
+	return &helloMessage, nil
+}
+
+// RawHello returns the exact bytes of the hello message received from
+// the server for auditing purposes, excluding the message separator and
+// any surrounding whitespace.
+// Unlike the decoded HelloMessage, it preserves vendor-specific elements.
+func (s *Session) RawHello() []byte {
+	return s.rawHello
+}
+
+// SessionID returns the session-id assigned by the server
+// in its hello message.
+func (s *Session) SessionID() uint {
+	return s.sessionID
+}
+
+// dialContext connects to the target, and performs the SSH handshake.
+// The connection is closed if the context is cancelled before the
+// handshake completes, which unblocks the handshake.
+func dialContext(ctx context.Context, clientConfig *ssh.ClientConfig, target string) (*ssh.Client, error) {
+
+	dialer := net.Dialer{Timeout: clientConfig.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", target)
+	if err != nil {
+		return nil, err
+	}
+
+	handshakeDone := make(chan struct{})
+	watchDone := make(chan struct{})
+
+	go func() {
+		defer close(watchDone)
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-handshakeDone:
+		}
+	}()
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, target, clientConfig)
+	close(handshakeDone)
+	<-watchDone
+
+	if err != nil {
+		_ = conn.Close()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
 	}
 
-	return &session, &helloMessage, nil
+	if err := ctx.Err(); err != nil {
+		_ = sshConn.Close()
+		return nil, err
+	}
+
+	return ssh.NewClient(sshConn, chans, reqs), nil
 }
 
 // NewReplyReader returns a ReplyReader that reads exactly one
@@ -81,8 +348,8 @@ func NewSession(clientConfig *ssh.ClientConfig, target string) (*Session, *Hello
 // error is returned. The io.EOF error is also returned on all subsequent
 // calls.
 //
-// The ReplyReader does not close the underlying session. Multiple
-// ReplyReaders are required to read multiple replies from the same session.
+// The ReplyReader does not close the underlying session. To read multiple
+// replies from the same session, call its Reset method between them.
 func (s *Session) NewReplyReader() *ReplyReader {
 	return NewReplyReader(s)
 }
@@ -107,6 +374,16 @@ func (s *Session) Write(p []byte) (n int, err error) {
 	return s.writeCloser.Write(p)
 }
 
+// SSHClient returns the SSH client the session runs over. It can be used
+// to open sibling channels (e.g. SFTP, or exec for diagnostics) over the
+// same TCP connection.
+//
+// The returned client is closed by Close, and should not be closed
+// directly.
+func (s *Session) SSHClient() *ssh.Client {
+	return s.sshClient
+}
+
 // Close closes all session resources in the following order:
 //
 //  1. stdin pipe
@@ -114,7 +391,19 @@ func (s *Session) Write(p []byte) (n int, err error) {
 //  3. SSH client
 //
 // Errors are returned with priority matching the same order.
+//
+// Close may be called while RPCs are pending, from another goroutine.
+// Closing the stdin pipe and the SSH session interrupts their pending
+// reads and writes, so they return a SessionClosedError promptly.
 func (s *Session) Close() error {
+	atomic.StoreInt32(&s.closed, 1)
+	return s.shutdown()
+}
+
+// shutdown closes all session resources like Close, but the session's
+// RPCs don't return a SessionClosedError, for closes done internally to
+// interrupt a read that missed its deadline.
+func (s *Session) shutdown() error {
 
 	var (
 		writeCloseErr      error
@@ -146,9 +435,61 @@ func (s *Session) Close() error {
 }
 
 // NewDecoder returns a new Decoder object attached to the stdout pipe
-// of the underlying SSH session.
+// of the underlying SSH session. Its reads are bound by the deadline
+// set with WithReadDeadline, if any.
 func (s *Session) NewDecoder() *Decoder {
-	return NewDecoder(s.reader)
+	return s.newDecoder(s.decoderReader())
+}
+
+// decoderReader returns the reader of the session's decoders, which is
+// bound by the read deadline, if any.
+func (s *Session) decoderReader() io.Reader {
+	if s.readDeadline > 0 {
+		return s.NewDeadlineReader(s.readDeadline)
+	}
+	return s.reader
+}
+
+// newDecoder returns a Decoder reading from r, counting
+// the replies it decodes in the session's Stats.
+func (s *Session) newDecoder(r io.Reader) *Decoder {
+
+	d := NewDecoder(r)
+	if s.counters != nil {
+		d.received = &s.counters.repliesReceived
+	}
+
+	return d
+}
+
+// readErrRecorder records the first error returned by its reader, so the
+// failures of the session can be told apart from those of decoding.
+type readErrRecorder struct {
+	reader io.Reader
+	err    error
+}
+
+// Read reads from the reader, and records its error.
+func (r *readErrRecorder) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+// WithReadDeadline sets a deadline for every read made while decoding
+// replies, including those made by Decoders returned by NewDecoder, and
+// returns the session. A zero deadline removes it. A read that misses
+// the deadline returns a DeadlineError, and closes the session, because
+// it is the only way to interrupt the pending read.
+//
+// The deadline bounds each read rather than the whole reply, so it can
+// be combined with a context, which bounds the whole operation. It must
+// not be changed while a reply is being decoded.
+func (s *Session) WithReadDeadline(deadline time.Duration) *Session {
+	s.readDeadline = deadline
+	return s
 }
 
 // NewTimeoutDecoder returns a new Decoder attached to the stdout pipe
@@ -164,6 +505,7 @@ type DeadlineError struct {
 	BeginTime time.Time
 	FailTime  time.Time
 	Deadline  time.Duration
+	Err       error // Err is the underlying timeout error.
 }
 
 // Error implements the error interface.
@@ -172,6 +514,11 @@ func (te *DeadlineError) Error() string {
 		te.Op, te.Deadline, te.BeginTime, te.FailTime)
 }
 
+// Unwrap returns the underlying timeout error.
+func (te *DeadlineError) Unwrap() error {
+	return te.Err
+}
+
 // NewDeadlineReader decorates the session's io.Reader with
 // a new DeadlineReader.
 //
@@ -179,17 +526,111 @@ func (te *DeadlineError) Error() string {
 // the stream. It does not handle higher level functionality,
 // like a complete implementation of an io.Reader, or discarding
 // NETCONF message separators.
+//
+// The session is closed when the deadline expires, because it is the
+// only way to interrupt the pending read.
 func (s *Session) NewDeadlineReader(deadline time.Duration) io.Reader {
 	return &DeadlineReader{
 		reader:   s.reader,
+		closer:   closerFunc(s.shutdown),
 		deadline: deadline,
 	}
 }
 
 // NewEncoder returns a new Encoder object attached to the stdin pipe
 // of the underlying SSH session.
+//
+// Once any Encoder of the session fails to write an RPC, every
+// Encoder of the session returns a SessionPoisonedError.
 func (s *Session) NewEncoder() *Encoder {
-	return NewEncoder(s.writeCloser)
+	e := NewEncoder(s.writeCloser)
+	e.poisoned = &s.writeErr
+	if s.counters != nil {
+		e.sent = &s.counters.rpcsSent
+	}
+	return e
+}
+
+// exec encodes the method, and decodes its reply into v. The session is
+// closed if the context is done before the reply is decoded, because it
+// is the only way to interrupt the pending read.
+func (s *Session) exec(ctx context.Context, method *Method, v interface{}) error {
+
+	if err := s.NewEncoder().EncodeContext(ctx, method); err != nil {
+		if err == ctx.Err() {
+			return err
+		}
+		return s.closedError(err)
+	}
+
+	return s.decodeContext(ctx, v)
+}
+
+// decodeContext decodes a reply into v, and discards its separator. If the
+// reply's data can't be decoded into v, the rest of the reply is discarded,
+// and the error is returned, or the session is closed, and the error is
+// returned in a SessionClosedError, if the reply can't be discarded. The
+// session is also closed if the context is done before the reply is decoded.
+func (s *Session) decodeContext(ctx context.Context, v interface{}) error {
+
+	// buffered so the decoding goroutine never blocks on send
+	ch := make(chan error, 1)
+	go func() {
+		rec := readErrRecorder{reader: s.decoderReader()}
+		dec := s.newDecoder(&rec)
+		err := dec.Decode(v)
+		switch err.(type) {
+		case nil, *ReplyError, *LockDeniedError, *MultiError:
+			// the reply was read entirely, so its separator follows
+			if sepErr := dec.SkipSep(); err == nil {
+				err = sepErr
+			}
+		default:
+			if rec.err != nil {
+				// the session failed, so it is the error returned
+				break
+			}
+			// the reply's data couldn't be decoded into v, so the rest of
+			// it is discarded, so the next RPC doesn't read it, and the
+			// session is closed if it can't be
+			if sepErr := dec.SkipSep(); sepErr != nil {
+				_ = s.shutdown()
+				err = &SessionClosedError{Err: err}
+			}
+		}
+		ch <- err
+	}()
+
+	select {
+	case err := <-ch:
+		return s.closedError(err)
+	case <-ctx.Done():
+		_ = s.shutdown()
+		<-ch
+		return ctx.Err()
+	}
+}
+
+// ExecReply sends the method, and returns its entire reply, so Ok, every
+// ReplyError, and the data can be inspected together. The reply's Data
+// field is a *[]RawElement, holding every element of the reply's data.
+//
+// The errors in the reply are also returned as a ReplyError, or a
+// MultiError, exactly like Decode, alongside the reply. The reply is
+// nil only if the method could not be sent, or the reply could not be
+// read. The session is closed if the context is done before the reply
+// is read.
+func (s *Session) ExecReply(ctx context.Context, method *Method) (*Reply, error) {
+
+	reply := Reply{Data: &[]RawElement{}}
+
+	err := s.exec(ctx, method, &reply)
+	switch err.(type) {
+	case nil, *ReplyError, *LockDeniedError, *MultiError:
+		return &reply, err
+	}
+
+	return nil, err
 }
 
 // TODO: Make RPCWriter that handles writing NETCONF message separators.
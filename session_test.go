@@ -0,0 +1,122 @@
+package netconf
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSession_Close_PendingRPC(t *testing.T) {
+
+	// the server never replies, until the test is done
+	release := make(chan struct{})
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		<-release
+		return nil
+	})
+	defer stop()
+	defer close(release)
+
+	ch := make(chan error, 1)
+	go func() {
+		ch <- session.Ping(context.Background())
+	}()
+
+	// give Ping the time to send its RPC and block reading the reply
+	time.Sleep(50 * time.Millisecond)
+	if err := session.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-ch:
+		var closedErr *SessionClosedError
+		if !errors.As(err, &closedErr) {
+			t.Errorf("unexpected error:\nwant:\t%T\ngot:\t%v", closedErr, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Ping still blocked one second after Close")
+	}
+
+	// an RPC started after Close fails the same way
+	var closedErr *SessionClosedError
+	if err := session.Ping(context.Background()); !errors.As(err, &closedErr) {
+		t.Errorf("unexpected error after Close:\nwant:\t%T\ngot:\t%v", closedErr, err)
+	}
+}
+
+func TestSession_Exec_DecodeError(t *testing.T) {
+
+	// the reply is larger than the decoder's buffer, so its tail is
+	// still unread when its data fails to decode
+	badReply := `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data><n>abc</n><filler>` +
+		strings.Repeat("x", 20<<10) + `</filler></data></rpc-reply>`
+	goodReply := `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data><n>42</n></data></rpc-reply>`
+
+	replies := 0
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		if replies++; replies == 1 {
+			return []byte(badReply)
+		}
+		return []byte(goodReply)
+	})
+	defer stop()
+
+	type Data struct {
+		XMLName xml.Name `xml:"data"`
+		N       int      `xml:"n"`
+	}
+
+	var data Data
+	err := session.ExecAll(context.Background(), []*Method{Get(Filter{})}, []interface{}{&data})
+
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		t.Fatalf("unexpected error:\nwant:\t%T\ngot:\t%v", numErr, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// the next RPC reads its own reply, not the tail of the previous one
+	if err := session.ExecAll(ctx, []*Method{Get(Filter{})}, []interface{}{&data}); err != nil {
+		t.Fatal(err)
+	} else if data.N != 42 {
+		t.Errorf("unexpected data\nwant:\t%d\ngot:\t%d", 42, data.N)
+	}
+}
+
+func TestSession_Exec_DecodeError_Closed(t *testing.T) {
+
+	// the server ends the session before the end of the reply
+	clientConn, serverConn := net.Pipe()
+	go func() {
+		defer serverConn.Close()
+		_, _ = io.ReadAll(NewReplyReader(serverConn))
+		_, _ = io.WriteString(serverConn, `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data><n>abc</n>`)
+	}()
+
+	var session Session
+	session.attach(clientConn, clientConn, nil)
+	defer session.Close()
+
+	var data struct {
+		XMLName xml.Name `xml:"data"`
+		N       int      `xml:"n"`
+	}
+	err := session.exec(context.Background(), Get(Filter{}), &data)
+
+	var closedErr *SessionClosedError
+	var numErr *strconv.NumError
+	if !errors.As(err, &closedErr) {
+		t.Errorf("unexpected error:\nwant:\t%T\ngot:\t%v", closedErr, err)
+	} else if !errors.As(err, &numErr) {
+		t.Errorf("SessionClosedError does not wrap the decoding error: %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package netconf
+
+import (
+	"sync/atomic"
+)
+
+// SessionStats is a snapshot of a session's traffic counters.
+type SessionStats struct {
+	BytesRead       uint64 // BytesRead is the number of bytes read from the server, including its hello.
+	BytesWritten    uint64 // BytesWritten is the number of bytes written to the server, including the hello.
+	RPCsSent        uint64 // RPCsSent is the number of RPCs sent by the session's Encoders.
+	RepliesReceived uint64 // RepliesReceived is the number of replies read entirely by the session's Decoders.
+}
+
+// sessionCounters holds the counters behind SessionStats. It is always
+// allocated on its own, so its fields are 64-bit aligned for atomic access.
+type sessionCounters struct {
+	bytesRead       uint64
+	bytesWritten    uint64
+	rpcsSent        uint64
+	repliesReceived uint64
+}
+
+// Stats returns a snapshot of the session's traffic counters. The counters
+// are updated atomically, so Stats is safe to call concurrently with RPCs.
+func (s *Session) Stats() SessionStats {
+
+	if s.counters == nil {
+		return SessionStats{}
+	}
+
+	return SessionStats{
+		BytesRead:       atomic.LoadUint64(&s.counters.bytesRead),
+		BytesWritten:    atomic.LoadUint64(&s.counters.bytesWritten),
+		RPCsSent:        atomic.LoadUint64(&s.counters.rpcsSent),
+		RepliesReceived: atomic.LoadUint64(&s.counters.repliesReceived),
+	}
+}
@@ -0,0 +1,41 @@
+package netconf
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSession_Stats(t *testing.T) {
+
+	const reply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data></data></rpc-reply>`
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		return []byte(reply)
+	})
+	defer stop()
+
+	before := session.Stats()
+	if before.BytesRead != uint64(len(TestServerHello)) {
+		t.Errorf("unexpected bytes read after the hello\nwant:\t%d\ngot:\t%d", len(TestServerHello), before.BytesRead)
+	} else if before.BytesWritten != uint64(len(DefaultHelloMessage)) {
+		t.Errorf("unexpected bytes written after the hello\nwant:\t%d\ngot:\t%d", len(DefaultHelloMessage), before.BytesWritten)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := session.Ping(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	after := session.Stats()
+	if after.RPCsSent != 2 || after.RepliesReceived != 2 {
+		t.Errorf("unexpected message counts\nwant:\t%d sent, %d received\ngot:\t%d sent, %d received",
+			2, 2, after.RPCsSent, after.RepliesReceived)
+	}
+
+	wantRead := before.BytesRead + 2*uint64(len(reply+MessageSeparator+"\n"))
+	if after.BytesRead != wantRead {
+		t.Errorf("unexpected bytes read\nwant:\t%d\ngot:\t%d", wantRead, after.BytesRead)
+	} else if after.BytesWritten <= before.BytesWritten {
+		t.Errorf("bytes written were not counted: %d", after.BytesWritten)
+	}
+}
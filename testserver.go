@@ -0,0 +1,92 @@
+package netconf
+
+import (
+	"bytes"
+	"io"
+	"net"
+)
+
+// TestServerHello is the hello message sent by the server
+// created with NewTestSession.
+const TestServerHello = `<?xml version="1.0" encoding="UTF-8"?>
+<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<capabilities>
+<capability>urn:ietf:params:netconf:base:1.0</capability>
+</capabilities>
+<session-id>1</session-id>
+</hello>
+]]>]]>
+`
+
+// NewTestSession returns a Session connected to an in-memory NETCONF
+// server, so RPCs can be tested without SSH or a real device. Hello
+// messages are exchanged before NewTestSession returns, and an error is
+// returned if the exchange fails.
+//
+// The server calls handler with every request it receives, without the
+// message separator and surrounding whitespace, and writes the returned
+// reply followed by a message separator. It advertises base:1.0 only, and
+// only the end-of-message framing is served, since it is the only framing
+// Session implements; the base:1.1 chunked framing is not supported.
+//
+// The returned function closes the session and stops the server.
+func NewTestSession(handler func(req []byte) []byte) (*Session, func(), error) {
+	return newTestSessionHello(TestServerHello, handler)
+}
+
+// newTestSessionHello is like NewTestSession, but the server
+// sends the given hello message instead of TestServerHello.
+func newTestSessionHello(serverHello string, handler func(req []byte) []byte) (*Session, func(), error) {
+
+	clientConn, serverConn := net.Pipe()
+	serverDone := make(chan struct{})
+
+	go func() {
+		defer close(serverDone)
+		defer serverConn.Close()
+		serveTestSession(serverConn, serverHello, handler)
+	}()
+
+	var session Session
+	session.attach(clientConn, clientConn, nil)
+
+	stop := func() {
+		_ = session.Close()
+		<-serverDone
+	}
+
+	if _, err := session.exchangeHello(nil); err != nil {
+		stop()
+		return nil, nil, err
+	}
+
+	return &session, stop, nil
+}
+
+// serveTestSession sends the server's hello, discards the client's hello,
+// and then replies to every request until the connection is closed.
+func serveTestSession(conn net.Conn, serverHello string, handler func(req []byte) []byte) {
+
+	if _, err := io.WriteString(conn, serverHello); err != nil {
+		return
+	}
+
+	replyReader := NewReplyReader(conn)
+	if _, err := io.ReadAll(replyReader); err != nil {
+		return
+	}
+
+	for {
+		replyReader.Reset()
+
+		req, err := io.ReadAll(replyReader)
+		if req = bytes.TrimSpace(req); err != nil || len(req) == 0 {
+			return
+		}
+
+		reply := append(handler(req), MessageSeparator+"\n"...)
+		if _, err := conn.Write(reply); err != nil {
+			return
+		}
+	}
+}
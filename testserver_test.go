@@ -0,0 +1,173 @@
+package netconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+// newTestSession is NewTestSession, failing the test if hello
+// messages could not be exchanged.
+func newTestSession(t testing.TB, handler func(req []byte) []byte) (*Session, func()) {
+	t.Helper()
+
+	session, stop, err := NewTestSession(handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return session, stop
+}
+
+func TestNewTestSession(t *testing.T) {
+
+	type GetSoftwareInformation struct {
+		XMLName xml.Name `xml:"get-software-information"`
+	}
+
+	type SoftwareInformation struct {
+		HostName string `xml:"host-name"`
+	}
+
+	var gotReq []byte
+	session, stop, err := NewTestSession(func(req []byte) []byte {
+		gotReq = req
+		return []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<software-information>
+<host-name>srx240</host-name>
+</software-information>
+</rpc-reply>`)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	if err := session.NewEncoder().Encode(WrapMethodID("1", &GetSoftwareInformation{})); err != nil {
+		t.Fatal(err)
+	}
+
+	var swInfo SoftwareInformation
+	if err := session.NewDecoder().Decode(&swInfo); err != nil {
+		t.Fatal(err)
+	}
+
+	wantReq := []byte(`<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1"><get-software-information></get-software-information></rpc>`)
+	if !bytes.Equal(wantReq, gotReq) {
+		t.Errorf("unexpected request received by server\nwant:\t%q\ngot:\t%q", wantReq, gotReq)
+	} else if want := "srx240"; want != swInfo.HostName {
+		t.Errorf("unexpected host name decoded\nwant:\t%q\ngot:\t%q", want, swInfo.HostName)
+	}
+}
+
+func TestSession_RawHello(t *testing.T) {
+
+	session, stop := newTestSession(t, func(req []byte) []byte { return nil })
+	defer stop()
+
+	want := bytes.TrimSpace(bytes.TrimSuffix(
+		bytes.TrimSpace([]byte(TestServerHello)), messageSeparatorBytes))
+
+	if got := session.RawHello(); !bytes.Equal(want, got) {
+		t.Errorf("unexpected raw hello\nwant:\t%q\ngot:\t%q", want, got)
+	}
+}
+
+func TestSession_SessionID(t *testing.T) {
+
+	session, stop := newTestSession(t, func(req []byte) []byte { return nil })
+	defer stop()
+
+	if want, got := uint(1), session.SessionID(); want != got {
+		t.Errorf("unexpected session-id\nwant:\t%d\ngot:\t%d", want, got)
+	}
+}
+
+func TestSession_NewEncoder_Poisoned(t *testing.T) {
+
+	type GetSoftwareInformation struct {
+		XMLName xml.Name `xml:"get-software-information"`
+	}
+
+	session, stop := newTestSession(t, func(req []byte) []byte { return nil })
+	stop()
+
+	if err := session.NewEncoder().Encode(&GetSoftwareInformation{}); err == nil {
+		t.Fatal("expected an error writing to a closed session")
+	}
+
+	err := session.NewEncoder().Encode(&GetSoftwareInformation{})
+	if poisonedErr, ok := err.(*SessionPoisonedError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", poisonedErr, err)
+	}
+}
+
+func TestSession_ExecReply(t *testing.T) {
+
+	type GetSoftwareInformation struct {
+		XMLName xml.Name `xml:"get-software-information"`
+	}
+
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		return []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="7">
+<software-information><host-name>srx240</host-name></software-information>
+<rpc-error>
+<error-type>application</error-type>
+<error-tag>operation-failed</error-tag>
+<error-severity>warning</error-severity>
+</rpc-error>
+<rpc-error>
+<error-type>application</error-type>
+<error-tag>operation-failed</error-tag>
+<error-severity>error</error-severity>
+<error-message>partial failure</error-message>
+</rpc-error>
+</rpc-reply>`)
+	})
+	defer stop()
+
+	reply, err := session.ExecReply(context.Background(), WrapMethodID("7", &GetSoftwareInformation{}))
+	if _, ok := err.(*ReplyError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", &ReplyError{}, err)
+	}
+
+	if reply == nil {
+		t.Fatal("expected a reply alongside the error")
+	} else if len(reply.Error) != 2 {
+		t.Errorf("unexpected reply error count:\nwant:\t%d\ngot:\t%d", 2, len(reply.Error))
+	} else if want := "7"; want != reply.MessageID() {
+		t.Errorf("unexpected message-id:\nwant:\t%q\ngot:\t%q", want, reply.MessageID())
+	}
+
+	data := *reply.Data.(*[]RawElement)
+	if len(data) != 1 {
+		t.Fatalf("unexpected data element count:\nwant:\t%d\ngot:\t%d", 1, len(data))
+	} else if want := "<host-name>srx240</host-name>"; want != string(data[0].InnerXML) {
+		t.Errorf("unexpected data\nwant:\t%q\ngot:\t%q", want, data[0].InnerXML)
+	}
+}
+
+func TestNewTestSession_HelloError(t *testing.T) {
+
+	const malformedHello = "<hello xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\"><capabilities>\n]]>]]>\n"
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		session, stop, err := newTestSessionHello(malformedHello, func(req []byte) []byte { return nil })
+		if err == nil {
+			stop()
+			t.Error("expected an error exchanging a malformed hello")
+		} else if session != nil || stop != nil {
+			t.Error("unexpected session returned with the error")
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("newTestSessionHello still blocked one second after a malformed hello")
+	}
+}
@@ -0,0 +1,142 @@
+package netconf
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrTxDone is returned by the methods of a Tx that was already
+// committed or rolled back.
+var ErrTxDone = errors.New("netconf: transaction already committed or rolled back")
+
+// Tx is a configuration transaction started by Session.Transaction, which
+// holds the lock of the datastore it edits until it is committed or rolled
+// back. It must not be used concurrently.
+type Tx struct {
+	session *Session
+	target  string // datastore edited by the transaction
+	done    bool   // set once committed or rolled back
+}
+
+// Transaction locks the datastore the configuration should be edited in, and
+// returns a Tx editing it. The candidate datastore is used if the server
+// advertises CapabilityCandidate, otherwise the running datastore is used if
+// it advertises CapabilityWritableRunning, and an UnsupportedCapabilityError
+// naming both capabilities is returned if it advertises neither.
+//
+// The transaction must be ended by Commit or Rollback, and Close should be
+// deferred, so the datastore is unlocked, and the candidate's changes are
+// discarded, whatever happens:
+//
+//	tx, err := session.Transaction(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	defer tx.Close()
+//
+//	if err := tx.EditConfig(ctx, config); err != nil {
+//		return err
+//	}
+//	return tx.Commit(ctx)
+func (s *Session) Transaction(ctx context.Context) (*Tx, error) {
+
+	var hello HelloMessage
+	if s.hello != nil {
+		hello = *s.hello
+	}
+
+	var target string
+	switch {
+	case hello.HasCapability(CapabilityCandidate):
+		target = DatastoreCandidate
+	case hello.HasCapability(CapabilityWritableRunning):
+		target = DatastoreRunning
+	default:
+		return nil, &UnsupportedCapabilityError{
+			Capability:   CapabilityCandidate,
+			Alternatives: []string{CapabilityWritableRunning},
+		}
+	}
+
+	if err := s.exec(ctx, Lock(target), nil); err != nil {
+		return nil, err
+	}
+
+	return &Tx{session: s, target: target}, nil
+}
+
+// Target returns the datastore edited by the transaction, either
+// DatastoreCandidate or DatastoreRunning.
+func (tx *Tx) Target() string {
+	return tx.target
+}
+
+// EditConfig loads the configuration into the transaction's datastore, like
+// EditConfig. Changes to the running datastore take effect immediately.
+func (tx *Tx) EditConfig(ctx context.Context, config interface{}) error {
+
+	if tx.done {
+		return ErrTxDone
+	}
+
+	return tx.session.exec(ctx, EditConfig(tx.target, config), nil)
+}
+
+// Validate validates the transaction's datastore, which requires
+// CapabilityValidate.
+func (tx *Tx) Validate(ctx context.Context) error {
+
+	if tx.done {
+		return ErrTxDone
+	}
+
+	return tx.session.exec(ctx, Validate(tx.target), nil)
+}
+
+// Commit commits the candidate datastore, and unlocks the transaction's
+// datastore. If the commit fails, the transaction is still open, so it
+// can be rolled back.
+func (tx *Tx) Commit(ctx context.Context) error {
+
+	if tx.done {
+		return ErrTxDone
+	}
+
+	if tx.target == DatastoreCandidate {
+		if err := tx.session.exec(ctx, Commit(), nil); err != nil {
+			return err
+		}
+	}
+
+	tx.done = true
+	return tx.session.exec(ctx, Unlock(tx.target), nil)
+}
+
+// Rollback discards the changes made to the candidate datastore, and
+// unlocks the transaction's datastore. The changes made to the running
+// datastore already took effect, so they can't be discarded, and it is
+// only unlocked.
+//
+// Like ApplyCandidate's cleanup, Rollback does not use a context of the
+// caller's, which may have caused the failure being rolled back. The first
+// error is returned as a CandidateError naming the failed operation.
+func (tx *Tx) Rollback() error {
+
+	if tx.done {
+		return ErrTxDone
+	}
+
+	tx.done = true
+	return tx.session.cleanup(tx.target, tx.target == DatastoreCandidate)
+}
+
+// Close rolls the transaction back, unless it was already committed or
+// rolled back, in which case it does nothing.
+func (tx *Tx) Close() error {
+
+	if tx.done {
+		return nil
+	}
+
+	return tx.Rollback()
+}
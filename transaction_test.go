@@ -0,0 +1,109 @@
+package netconf
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSession_Transaction(t *testing.T) {
+
+	config := &struct {
+		XMLName xml.Name `xml:"system"`
+	}{}
+
+	tests := []struct {
+		name         string
+		capabilities []string
+		failing      string
+		rollback     bool
+		want         []string
+	}{
+		{
+			name:         "CandidateCommit",
+			capabilities: []string{CapabilityCandidate, CapabilityWritableRunning},
+			want:         []string{"lock", "edit-config", "validate", "commit", "unlock"},
+		},
+		{
+			name:         "CandidateRollback",
+			capabilities: []string{CapabilityCandidate},
+			failing:      "validate",
+			rollback:     true,
+			want:         []string{"lock", "edit-config", "validate", "discard-changes", "unlock"},
+		},
+		{
+			name:         "RunningCommit",
+			capabilities: []string{CapabilityWritableRunning},
+			want:         []string{"lock", "edit-config", "validate", "unlock"},
+		},
+		{
+			name:         "RunningRollback",
+			capabilities: []string{CapabilityWritableRunning},
+			failing:      "edit-config",
+			rollback:     true,
+			want:         []string{"lock", "edit-config", "unlock"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			var ops []string
+			session, stop := newTestSession(t, candidateServer(&ops, test.failing))
+			defer stop()
+			session.hello = &HelloMessage{Capabilities: test.capabilities}
+
+			tx, err := session.Transaction(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = func() error {
+				defer tx.Close()
+				if err := tx.EditConfig(context.Background(), config); err != nil {
+					return err
+				} else if err = tx.Validate(context.Background()); err != nil {
+					return err
+				}
+				return tx.Commit(context.Background())
+			}()
+
+			if test.rollback && err == nil {
+				t.Error("expected the failing operation's error, got nil")
+			} else if !test.rollback && err != nil {
+				t.Error(err)
+			}
+
+			if !reflect.DeepEqual(test.want, ops) {
+				t.Errorf("unexpected operations\nwant:\t%q\ngot:\t%q", test.want, ops)
+			}
+
+			if err := tx.Commit(context.Background()); !errors.Is(err, ErrTxDone) {
+				t.Errorf("unexpected error after the transaction ended:\nwant:\t%v\ngot:\t%v", ErrTxDone, err)
+			}
+		})
+	}
+}
+
+func TestSession_Transaction_Unsupported(t *testing.T) {
+
+	var ops []string
+	session, stop := newTestSession(t, candidateServer(&ops, ""))
+	defer stop()
+
+	_, err := session.Transaction(context.Background())
+
+	var capErr *UnsupportedCapabilityError
+	if !errors.As(err, &capErr) {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", capErr, err)
+	} else if want := []string{CapabilityWritableRunning}; !reflect.DeepEqual(want, capErr.Alternatives) {
+		t.Errorf("unexpected alternatives\nwant:\t%q\ngot:\t%q", want, capErr.Alternatives)
+	} else if !strings.Contains(err.Error(), CapabilityCandidate) || !strings.Contains(err.Error(), CapabilityWritableRunning) {
+		t.Errorf("error does not name both capabilities: %v", err)
+	} else if len(ops) != 0 {
+		t.Errorf("operations sent without a writable datastore: %q", ops)
+	}
+}
@@ -0,0 +1,43 @@
+package netconf
+
+import (
+	"context"
+	"encoding/xml"
+	"os"
+)
+
+// CapabilityValidate is the capability a server advertises when it
+// supports the validate operation.
+const CapabilityValidate = "urn:ietf:params:netconf:capability:validate:1.1"
+
+// ValidateFile validates the configuration in the file at the given
+// path, using the validate operation, without applying it. The file
+// is streamed into the RPC, just like CopyConfigFrom, so it is never
+// held in memory entirely.
+//
+// The file must contain the children of the config element. If the
+// configuration is invalid, the server's ReplyError, or a MultiError,
+// is returned. The server must advertise CapabilityValidate.
+func (s *Session) ValidateFile(ctx context.Context, path string) error {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return s.execConfigFrom(ctx, "validate", nil, f)
+}
+
+// validateOperation models the validate operation.
+type validateOperation struct {
+	XMLName xml.Name       `xml:"validate"`
+	Source  datastoreParam `xml:"source"`
+}
+
+// Validate returns a Method that validates the configuration in the
+// given datastore (e.g. DatastoreCandidate), without applying it. The
+// server must advertise CapabilityValidate.
+func Validate(source string) *Method {
+	return WrapMethod(&validateOperation{Source: datastoreParam(source)})
+}
@@ -0,0 +1,61 @@
+package netconf
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSession_ValidateFile(t *testing.T) {
+
+	var gotReq []byte
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		gotReq = req
+		return []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<rpc-error>
+<error-type>application</error-type>
+<error-tag>invalid-value</error-tag>
+<error-severity>error</error-severity>
+<error-message>MTU out of range</error-message>
+</rpc-error>
+</rpc-reply>`)
+	})
+	defer stop()
+
+	path := filepath.Join(t.TempDir(), "config.xml")
+	config := `<interfaces xmlns="urn:ietf:params:xml:ns:yang:ietf-interfaces"><interface><name>eth0</name><mtu>99999</mtu></interface></interfaces>`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := session.ValidateFile(context.Background(), path)
+	if replyErr, ok := err.(*ReplyError); !ok {
+		t.Errorf("unexpected error type:\nwant:\t%T\ngot:\t%T", replyErr, err)
+	} else if replyErr.Tag != ErrorTagInvalidValue {
+		t.Errorf("unexpected error tag:\nwant:\t%q\ngot:\t%q", ErrorTagInvalidValue, replyErr.Tag)
+	}
+
+	want := []byte(`<validate><source><config>` + config + `</config></source></validate></rpc>`)
+	if !bytes.HasSuffix(gotReq, want) {
+		t.Errorf("unexpected request received by server\nwant:\t%q\ngot:\t%q", want, gotReq)
+	}
+
+	// the file is validated as written, prefix declarations included
+	prefixed := `<native xmlns="http://cisco.com/ns/yang/Cisco-IOS-XE-native" xmlns:ios-eth="http://cisco.com/ns/yang/Cisco-IOS-XE-ethernet">` +
+		`<interface><GigabitEthernet><name>1</name><ios-eth:negotiation><ios-eth:auto>true</ios-eth:auto></ios-eth:negotiation></GigabitEthernet></interface></native>`
+	if err := os.WriteFile(path, []byte(prefixed), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = session.ValidateFile(context.Background(), path)
+	want = []byte(`<validate><source><config>` + prefixed + `</config></source></validate></rpc>`)
+	if !bytes.HasSuffix(gotReq, want) {
+		t.Errorf("unexpected request received by server\nwant:\t%q\ngot:\t%q", want, gotReq)
+	}
+
+	if err := session.ValidateFile(context.Background(), filepath.Join(t.TempDir(), "missing.xml")); !os.IsNotExist(err) {
+		t.Errorf("unexpected error validating a missing file: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package netconf
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// attach connects the session to the server's stdout and stdin streams,
+// counting, and tracing to the given io.Writer, if any, every byte read
+// and written, starting with the hello messages.
+func (s *Session) attach(r io.Reader, wc io.WriteCloser, trace io.Writer) {
+	s.counters = new(sessionCounters)
+	s.tracer = &tracer{w: trace}
+	s.reader = &wireReader{Reader: r, n: &s.counters.bytesRead, tracer: s.tracer}
+	s.writeCloser = &wireWriteCloser{WriteCloser: wc, n: &s.counters.bytesWritten, tracer: s.tracer}
+}
+
+// SetTrace starts copying every byte read from and written to the server,
+// exactly as it is on the wire, including message separators, to the given
+// io.Writer, which is useful when debugging a device that can't be captured
+// with tcpdump. Every read and write is written on its own, after a header
+// line holding a timestamp, the direction ("recv" or "send"), and the number
+// of bytes. A nil io.Writer stops tracing. The hello messages are exchanged
+// before SetTrace can be called, so SessionConfig.Trace traces them too.
+//
+// Errors writing the trace are ignored. Reads and writes are traced one at
+// a time, so w doesn't need to be safe for concurrent use.
+func (s *Session) SetTrace(w io.Writer) {
+	if s.tracer != nil {
+		s.tracer.setWriter(w)
+	}
+}
+
+// tracer writes the bytes read and written by a session to an io.Writer.
+type tracer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// setWriter replaces the io.Writer the trace is written to.
+func (t *tracer) setWriter(w io.Writer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.w = w
+}
+
+// trace writes the bytes, read or written in the given direction.
+func (t *tracer) trace(direction string, p []byte) {
+
+	if len(p) == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.w == nil {
+		return
+	}
+
+	_, _ = fmt.Fprintf(t.w, "%s %s %d bytes\n%s\n",
+		time.Now().UTC().Format(time.RFC3339Nano), direction, len(p), p)
+}
+
+// wireReader counts, and traces, the bytes read from the embedded io.Reader.
+type wireReader struct {
+	io.Reader
+	n      *uint64
+	tracer *tracer
+}
+
+// Read implements the io.Reader interface.
+func (wr *wireReader) Read(p []byte) (int, error) {
+	n, err := wr.Reader.Read(p)
+	atomic.AddUint64(wr.n, uint64(n))
+	wr.tracer.trace("recv", p[:n])
+	return n, err
+}
+
+// wireWriteCloser counts, and traces, the bytes written to the embedded
+// io.WriteCloser.
+type wireWriteCloser struct {
+	io.WriteCloser
+	n      *uint64
+	tracer *tracer
+}
+
+// Write implements the io.Writer interface.
+func (ww *wireWriteCloser) Write(p []byte) (int, error) {
+	n, err := ww.WriteCloser.Write(p)
+	atomic.AddUint64(ww.n, uint64(n))
+	ww.tracer.trace("send", p[:n])
+	return n, err
+}
@@ -0,0 +1,77 @@
+package netconf
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"regexp"
+	"testing"
+)
+
+func TestSession_SetTrace(t *testing.T) {
+
+	const reply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data></data></rpc-reply>`
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		return []byte(reply)
+	})
+	defer stop()
+
+	var trace bytes.Buffer
+	session.SetTrace(&trace)
+
+	if err := session.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	session.SetTrace(nil)
+
+	got := trace.String()
+	for _, want := range []string{
+		`(?m)^\S+ send \d+ bytes\n<rpc `,
+		`\]\]>\]\]>\n`,
+		`(?m)^\S+ recv \d+ bytes\n` + regexp.QuoteMeta(reply),
+	} {
+		if !regexp.MustCompile(want).MatchString(got) {
+			t.Errorf("trace does not match %q\ngot:\t%q", want, got)
+		}
+	}
+
+	// tracing stopped, so nothing else is written
+	traced := trace.Len()
+	if err := session.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if trace.Len() != traced {
+		t.Errorf("trace was written after it was stopped: %q", trace.Bytes()[traced:])
+	}
+}
+
+func TestSession_Trace_Hello(t *testing.T) {
+
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	go func() {
+		_, _ = io.WriteString(serverConn, TestServerHello)
+		_, _ = io.Copy(io.Discard, serverConn)
+	}()
+
+	var trace bytes.Buffer
+	var session Session
+	session.attach(clientConn, clientConn, &trace)
+	defer session.Close()
+
+	if _, err := session.exchangeHelloTimeout(0, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got := trace.String()
+	for _, want := range []string{
+		`(?m)^\S+ recv \d+ bytes\n` + regexp.QuoteMeta(`<?xml version="1.0" encoding="UTF-8"?>`),
+		`<session-id>1</session-id>`,
+		`(?m)^\S+ send \d+ bytes\n` + regexp.QuoteMeta(`<?xml version="1.0" encoding="UTF-8"?>`),
+		regexp.QuoteMeta(`<capability>urn:ietf:params:netconf:base:1.1</capability>`),
+	} {
+		if !regexp.MustCompile(want).MatchString(got) {
+			t.Errorf("trace does not match %q\ngot:\t%q", want, got)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package netconf
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+)
+
+const (
+	// YANGLibraryNamespace is the namespace of the ietf-yang-library module.
+	YANGLibraryNamespace = "urn:ietf:params:xml:ns:yang:ietf-yang-library"
+
+	// YANGLibrary2016 is the revision of ietf-yang-library (RFC 7895)
+	// with the modules-state layout.
+	YANGLibrary2016 = "2016-06-21"
+
+	// YANGLibrary2019 is the revision of ietf-yang-library (RFC 8525)
+	// with the yang-library layout.
+	YANGLibrary2019 = "2019-01-04"
+)
+
+// ErrNoYANGLibrary is returned by YANGLibrary when the reply holds neither
+// the yang-library nor the modules-state container, e.g. because the
+// server does not implement ietf-yang-library.
+var ErrNoYANGLibrary = errors.New("netconf: no ietf-yang-library data in the reply")
+
+// YANGModule describes a YANG module implemented by the server.
+type YANGModule struct {
+	Name      string   `xml:"name"`
+	Revision  string   `xml:"revision"`
+	Namespace string   `xml:"namespace"`
+	Features  []string `xml:"feature"`
+}
+
+// YANGLibrary is the list of YANG modules supported by the server.
+type YANGLibrary struct {
+	// Revision is the ietf-yang-library revision whose layout was found,
+	// either YANGLibrary2019 or YANGLibrary2016.
+	Revision string
+
+	// Modules are the implemented modules. In the YANGLibrary2019 layout,
+	// the modules of every module-set are included.
+	Modules []YANGModule
+}
+
+// yangLibraryFilter selects both the yang-library and the older
+// modules-state containers, since either may be implemented.
+var yangLibraryFilter = []interface{}{
+	&struct {
+		XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-yang-library yang-library"`
+	}{},
+	&struct {
+		XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-yang-library modules-state"`
+	}{},
+}
+
+// yangLibraryData models the data of the reply to the yang library get.
+// The containers are pointers, so their presence is known even without modules.
+type yangLibraryData struct {
+	XMLName     xml.Name `xml:"data"`
+	YANGLibrary *struct {
+		ModuleSets []struct {
+			Modules []YANGModule `xml:"module"`
+		} `xml:"module-set"`
+	} `xml:"yang-library"`
+	ModulesState *struct {
+		Modules []YANGModule `xml:"module"`
+	} `xml:"modules-state"`
+}
+
+// YANGLibrary gets the list of YANG modules supported by the server, as
+// reported by the ietf-yang-library module. The 2019 yang-library layout
+// is preferred when the server reports both, and ErrNoYANGLibrary is
+// returned when it reports neither.
+//
+// The session is closed if the context is done before the reply is read.
+func (s *Session) YANGLibrary(ctx context.Context) (*YANGLibrary, error) {
+
+	var data yangLibraryData
+	if err := s.exec(ctx, Get(SubtreeFilter(yangLibraryFilter)), &data); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case data.YANGLibrary != nil:
+		lib := YANGLibrary{Revision: YANGLibrary2019}
+		for _, moduleSet := range data.YANGLibrary.ModuleSets {
+			lib.Modules = append(lib.Modules, moduleSet.Modules...)
+		}
+		return &lib, nil
+	case data.ModulesState != nil:
+		return &YANGLibrary{
+			Revision: YANGLibrary2016,
+			Modules:  data.ModulesState.Modules,
+		}, nil
+	}
+
+	return nil, ErrNoYANGLibrary
+}
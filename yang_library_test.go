@@ -0,0 +1,108 @@
+package netconf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSession_YANGLibrary(t *testing.T) {
+
+	const yangLibrary2019 = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<data>
+<yang-library xmlns="urn:ietf:params:xml:ns:yang:ietf-yang-library">
+<module-set>
+<name>complete</name>
+<module>
+<name>ietf-interfaces</name>
+<revision>2018-02-20</revision>
+<namespace>urn:ietf:params:xml:ns:yang:ietf-interfaces</namespace>
+<feature>if-mib</feature>
+</module>
+</module-set>
+</yang-library>
+</data>
+</rpc-reply>`
+
+	const yangLibrary2016 = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<data>
+<modules-state xmlns="urn:ietf:params:xml:ns:yang:ietf-yang-library">
+<module-set-id>1</module-set-id>
+<module>
+<name>ietf-interfaces</name>
+<revision>2014-05-08</revision>
+<namespace>urn:ietf:params:xml:ns:yang:ietf-interfaces</namespace>
+<conformance-type>implement</conformance-type>
+</module>
+</modules-state>
+</data>
+</rpc-reply>`
+
+	tests := []struct {
+		Reply string
+		Want  YANGLibrary
+	}{
+		{
+			Reply: yangLibrary2019,
+			Want: YANGLibrary{
+				Revision: YANGLibrary2019,
+				Modules: []YANGModule{{
+					Name:      "ietf-interfaces",
+					Revision:  "2018-02-20",
+					Namespace: "urn:ietf:params:xml:ns:yang:ietf-interfaces",
+					Features:  []string{"if-mib"},
+				}},
+			},
+		},
+		{
+			Reply: yangLibrary2016,
+			Want: YANGLibrary{
+				Revision: YANGLibrary2016,
+				Modules: []YANGModule{{
+					Name:      "ietf-interfaces",
+					Revision:  "2014-05-08",
+					Namespace: "urn:ietf:params:xml:ns:yang:ietf-interfaces",
+				}},
+			},
+		},
+	}
+
+	for i, test := range tests {
+
+		var gotReq []byte
+		session, stop := newTestSession(t, func(req []byte) []byte {
+			gotReq = req
+			return []byte(test.Reply)
+		})
+
+		lib, err := session.YANGLibrary(context.Background())
+		stop()
+
+		wantFilter := []byte(`<filter type="subtree"><yang-library xmlns="urn:ietf:params:xml:ns:yang:ietf-yang-library"></yang-library><modules-state xmlns="urn:ietf:params:xml:ns:yang:ietf-yang-library"></modules-state></filter>`)
+
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+		} else if !reflect.DeepEqual(&test.Want, lib) {
+			t.Errorf("test %d: unexpected yang library\nwant:\t%+v\ngot:\t%+v", i, &test.Want, lib)
+		} else if !bytes.Contains(gotReq, wantFilter) {
+			t.Errorf("test %d: request does not contain the filter\nwant:\t%q\ngot:\t%q", i, wantFilter, gotReq)
+		}
+	}
+}
+
+func TestSession_YANGLibrary_NotFound(t *testing.T) {
+
+	session, stop := newTestSession(t, func(req []byte) []byte {
+		return []byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data></data></rpc-reply>`)
+	})
+	defer stop()
+
+	lib, err := session.YANGLibrary(context.Background())
+	if !errors.Is(err, ErrNoYANGLibrary) {
+		t.Errorf("unexpected error:\nwant:\t%v\ngot:\t%v", ErrNoYANGLibrary, err)
+	} else if lib != nil {
+		t.Errorf("unexpected yang library: %+v", lib)
+	}
+}